@@ -0,0 +1,131 @@
+package fast_lem
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// AccessLog writes one JSON object per line describing each request served
+type AccessLog struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewAccessLog returns an AccessLog writing to w
+func NewAccessLog(w io.Writer) *AccessLog {
+	return &AccessLog{enc: json.NewEncoder(w)}
+}
+
+// AccessLogEntry describes one request.  The key counts are only set by handlers that
+// look up keys.
+type AccessLogEntry struct {
+	Time       time.Time `json:"time"`
+	Remote     string    `json:"remote"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Handler    string    `json:"handler"`
+	Status     int       `json:"status"`
+	Bytes      int64     `json:"bytes"`
+	DurationMS float64   `json:"duration_ms"`
+	Keys       int       `json:"keys,omitempty"`
+	Found      int       `json:"found,omitempty"`
+	NotFound   int       `json:"not_found,omitempty"`
+	Invalid    int       `json:"invalid,omitempty"`
+	Failed     int       `json:"failed,omitempty"`
+	Denied     int       `json:"denied,omitempty"`
+	Terminated int       `json:"terminated,omitempty"`
+}
+
+// Log writes e as a line of JSON; it does nothing if al is nil
+func (al *AccessLog) Log(e *AccessLogEntry) {
+	if al == nil {
+		return
+	}
+	al.mu.Lock()
+	al.enc.Encode(e)
+	al.mu.Unlock()
+}
+
+type accessLogKey struct{}
+
+// entryFrom returns the AccessLogEntry of the request ctx belongs to, if it is being
+// logged
+func entryFrom(ctx context.Context) *AccessLogEntry {
+	e, _ := ctx.Value(accessLogKey{}).(*AccessLogEntry)
+	return e
+}
+
+// countResults records the outcomes of a batch lookup in the request's access log and
+// audit log entries
+func countResults(ctx context.Context, results []Result) {
+	var keys, found, notFound, invalid, failed, denied, terminated int
+	keys = len(results)
+	for _, r := range results {
+		switch r.Status {
+		case Found:
+			found++
+		case NotFound:
+			notFound++
+		case Invalid:
+			invalid++
+		case Denied:
+			denied++
+		case Terminated:
+			terminated++
+		default:
+			failed++
+		}
+	}
+	if e := entryFrom(ctx); e != nil {
+		e.Keys, e.Found, e.NotFound, e.Invalid, e.Failed, e.Denied = keys, found, notFound, invalid, failed, denied
+		e.Terminated = terminated
+	}
+	if e, ok := ctx.Value(auditKey{}).(*AuditEntry); ok {
+		e.Keys, e.Found, e.NotFound, e.Invalid, e.Failed, e.Denied = keys, found, notFound, invalid, failed, denied
+		e.Terminated = terminated
+	}
+}
+
+// Instrument wraps h so that each request it serves is counted and timed in m under
+// the name handler, and written to al.  Either of m and al may be nil.
+func Instrument(handler string, h http.HandlerFunc, m *Metrics, al *AccessLog) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		e := &AccessLogEntry{
+			Time:    time.Now(),
+			Remote:  r.RemoteAddr,
+			Method:  r.Method,
+			Path:    r.URL.Path,
+			Handler: handler,
+		}
+		rw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		h(rw, r.WithContext(context.WithValue(r.Context(), accessLogKey{}, e)))
+		d := time.Now().Sub(e.Time)
+		m.ObserveRequest(handler, rw.status, d)
+		e.Status = rw.status
+		e.Bytes = rw.bytes
+		e.DurationMS = float64(d) / float64(time.Millisecond)
+		al.Log(e)
+	}
+}
+
+// statusWriter remembers the status code and size of a response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (sw *statusWriter) WriteHeader(code int) {
+	sw.status = code
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+func (sw *statusWriter) Write(p []byte) (int, error) {
+	n, err := sw.ResponseWriter.Write(p)
+	sw.bytes += int64(n)
+	return n, err
+}
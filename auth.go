@@ -0,0 +1,258 @@
+package fast_lem
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnauthenticated = errors.New("missing or unknown API key or client certificate")
+)
+
+// Client is a consumer of the lookup API and its quotas
+type Client struct {
+	Name string
+	// Keys are API keys the client presents in an X-API-Key header or as an
+	// "Authorization: Bearer" token
+	Keys []string `json:",omitempty"`
+	// CertNames are the common names and DNS names of client certificates that
+	// identify the client over mutual TLS
+	CertNames []string `json:",omitempty"`
+	// RequestsPerSecond and Burst limit the client's request rate; zero means no
+	// limit.  Burst defaults to RequestsPerSecond, or 1 if that is smaller.
+	RequestsPerSecond float64 `json:",omitempty"`
+	Burst             int     `json:",omitempty"`
+	// MaxKeys, if positive, limits the number of keys in one query
+	MaxKeys int `json:",omitempty"`
+	// Entitlement limits the fields and identifier types the client may see
+	Entitlement
+
+	once    sync.Once
+	limiter *rateLimiter
+}
+
+// allow reports whether the client may make a request now, and if not how long it
+// must wait
+func (c *Client) allow() (bool, time.Duration) {
+	c.once.Do(func() {
+		if c.RequestsPerSecond > 0 {
+			burst := float64(c.Burst)
+			if burst <= 0 {
+				burst = math.Max(c.RequestsPerSecond, 1)
+			}
+			c.limiter = newRateLimiter(c.RequestsPerSecond, burst)
+		}
+	})
+	if c.limiter == nil {
+		return true, 0
+	}
+	return c.limiter.allow(time.Now())
+}
+
+// Authenticator identifies the client making a request
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Client, error)
+}
+
+// ClientRegistry authenticates the clients listed in a configuration file by API key
+// or by verified client certificate
+type ClientRegistry struct {
+	Clients []*Client
+
+	byKey  map[[sha256.Size]byte]*Client
+	byCert map[string]*Client
+}
+
+// LoadClientRegistry reads a JSON ClientRegistry from path
+func LoadClientRegistry(path string) (*ClientRegistry, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cr := &ClientRegistry{}
+	err = json.Unmarshal(data, cr)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %s", path, err)
+	}
+	return cr, cr.index()
+}
+
+// NewClientRegistry returns a ClientRegistry of clients
+func NewClientRegistry(clients ...*Client) (*ClientRegistry, error) {
+	cr := &ClientRegistry{Clients: clients}
+	return cr, cr.index()
+}
+
+func (cr *ClientRegistry) index() error {
+	cr.byKey = make(map[[sha256.Size]byte]*Client)
+	cr.byCert = make(map[string]*Client)
+	for _, c := range cr.Clients {
+		if len(c.Name) == 0 {
+			return errors.New("client with no name")
+		}
+		if err := c.Entitlement.validate(); err != nil {
+			return fmt.Errorf("client %s: %s", c.Name, err)
+		}
+		for _, k := range c.Keys {
+			h := sha256.Sum256([]byte(k))
+			if other, ok := cr.byKey[h]; ok {
+				return fmt.Errorf("clients %s and %s share an API key", other.Name, c.Name)
+			}
+			cr.byKey[h] = c
+		}
+		for _, n := range c.CertNames {
+			if other, ok := cr.byCert[n]; ok {
+				return fmt.Errorf("clients %s and %s share certificate name %s", other.Name, c.Name, n)
+			}
+			cr.byCert[n] = c
+		}
+	}
+	return nil
+}
+
+// Authenticate identifies the client by its verified TLS client certificate if it
+// presented one, and otherwise by its API key
+func (cr *ClientRegistry) Authenticate(r *http.Request) (*Client, error) {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		cert := r.TLS.VerifiedChains[0][0]
+		for _, n := range append([]string{cert.Subject.CommonName}, cert.DNSNames...) {
+			if c, ok := cr.byCert[n]; ok {
+				return c, nil
+			}
+		}
+	}
+	key := r.Header.Get("X-API-Key")
+	if auth := r.Header.Get("Authorization"); len(key) == 0 && strings.HasPrefix(auth, "Bearer ") {
+		key = strings.TrimPrefix(auth, "Bearer ")
+	}
+	if len(key) > 0 {
+		// keys are looked up by hash so the lookup does not depend on their content
+		if c, ok := cr.byKey[sha256.Sum256([]byte(key))]; ok {
+			return c, nil
+		}
+	}
+	return nil, ErrUnauthenticated
+}
+
+type clientKey struct{}
+
+// ClientFrom returns the authenticated client making the request ctx belongs to, or
+// nil if the request was not authenticated
+func ClientFrom(ctx context.Context) *Client {
+	c, _ := ctx.Value(clientKey{}).(*Client)
+	return c
+}
+
+// Guard wraps h so that only clients identified by a are served, within their rate
+// limits, and each request is written to audit, which may be nil
+func Guard(a Authenticator, audit *AuditLog, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		e := &AuditEntry{
+			Time:   time.Now(),
+			Remote: r.RemoteAddr,
+			Path:   r.URL.Path,
+		}
+		c, err := a.Authenticate(r)
+		if err != nil {
+			e.Status = http.StatusUnauthorized
+			audit.Log(e)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="lem"`)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		e.Client = c.Name
+		if ok, wait := c.allow(); !ok {
+			e.Status = http.StatusTooManyRequests
+			audit.Log(e)
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "Rate limit exceeded for client "+c.Name, http.StatusTooManyRequests)
+			return
+		}
+		rw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		ctx := context.WithValue(r.Context(), clientKey{}, c)
+		h(rw, r.WithContext(context.WithValue(ctx, auditKey{}, e)))
+		e.Status = rw.status
+		audit.Log(e)
+	}
+}
+
+// AuditLog writes one JSON object per line recording who looked up how many
+// identifiers
+type AuditLog struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewAuditLog returns an AuditLog writing to w
+func NewAuditLog(w io.Writer) *AuditLog {
+	return &AuditLog{enc: json.NewEncoder(w)}
+}
+
+// AuditEntry records one request by a client.  Client is empty for requests that
+// failed authentication.
+type AuditEntry struct {
+	Time       time.Time `json:"time"`
+	Client     string    `json:"client"`
+	Remote     string    `json:"remote"`
+	Path       string    `json:"path"`
+	Status     int       `json:"status"`
+	Keys       int       `json:"keys"`
+	Found      int       `json:"found"`
+	NotFound   int       `json:"not_found"`
+	Invalid    int       `json:"invalid"`
+	Failed     int       `json:"failed"`
+	Denied     int       `json:"denied"`
+	Terminated int       `json:"terminated"`
+}
+
+// Log writes e as a line of JSON; it does nothing if al is nil
+func (al *AuditLog) Log(e *AuditEntry) {
+	if al == nil {
+		return
+	}
+	al.mu.Lock()
+	al.enc.Encode(e)
+	al.mu.Unlock()
+}
+
+type auditKey struct{}
+
+// rateLimiter is a token bucket refilled at rate tokens per second up to burst
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate, burst float64) *rateLimiter {
+	return &rateLimiter{rate: rate, burst: burst, tokens: burst}
+}
+
+// allow takes a token if one is available at now, and otherwise reports how long
+// until one will be
+func (rl *rateLimiter) allow(now time.Time) (bool, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if !rl.last.IsZero() {
+		rl.tokens = math.Min(rl.burst, rl.tokens+now.Sub(rl.last).Seconds()*rl.rate)
+	}
+	rl.last = now
+	if rl.tokens >= 1 {
+		rl.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - rl.tokens) / rl.rate * float64(time.Second))
+}
@@ -0,0 +1,74 @@
+package fast_lem
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGuard(t *testing.T) {
+	registry, err := NewClientRegistry(
+		&Client{Name: "risk", Keys: []string{"secret"}, RequestsPerSecond: 0.001, Burst: 2, MaxKeys: 1},
+		&Client{Name: "ops", Keys: []string{"other"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := testSecurities()
+	server := Server{Getter: mapGetter{s[0].CUSIP: s[0]}}
+	var audited bytes.Buffer
+	h := Guard(registry, NewAuditLog(&audited), server.QueryHandler)
+	query := func(header, value, body string) int {
+		r := httptest.NewRequest("POST", "/query", strings.NewReader(body))
+		if len(header) > 0 {
+			r.Header.Set(header, value)
+		}
+		w := httptest.NewRecorder()
+		h(w, r)
+		return w.Code
+	}
+	for _, c := range []struct {
+		header, value, body string
+		want                int
+	}{
+		{"", "", `{"Keys":["851500000"]}`, http.StatusUnauthorized},
+		{"X-API-Key", "wrong", `{"Keys":["851500000"]}`, http.StatusUnauthorized},
+		{"X-API-Key", "secret", `{"Keys":["851500000"]}`, http.StatusOK},
+		{"Authorization", "Bearer secret", `{"Keys":["851500000","NOPE00000"]}`, http.StatusRequestEntityTooLarge},
+		{"X-API-Key", "secret", `{"Keys":["851500000"]}`, http.StatusTooManyRequests},
+		{"X-API-Key", "other", `{"Keys":["851500000","NOPE00000"]}`, http.StatusOK},
+	} {
+		if got := query(c.header, c.value, c.body); got != c.want {
+			t.Errorf("%s %s %s: got status %d, want %d", c.header, c.value, c.body, got, c.want)
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(audited.String()), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("Got %d audit entries, want 6", len(lines))
+	}
+	var e AuditEntry
+	if err = json.Unmarshal([]byte(lines[5]), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Client != "ops" || e.Status != 200 || e.Keys != 2 || e.Found != 1 || e.NotFound != 1 {
+		t.Errorf("Unexpected audit entry %s", lines[5])
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	rl := newRateLimiter(2, 1)
+	now := time.Now()
+	if ok, _ := rl.allow(now); !ok {
+		t.Error("First request refused")
+	}
+	if ok, wait := rl.allow(now); ok || wait != 500*time.Millisecond {
+		t.Errorf("Got %v, %s for an immediate second request, want refused for 500ms", ok, wait)
+	}
+	if ok, _ := rl.allow(now.Add(500 * time.Millisecond)); !ok {
+		t.Error("Request refused after the bucket refilled")
+	}
+}
@@ -0,0 +1,231 @@
+// Package bond computes fixed-income analytics for the bonds, notes and other debt in
+// the security master: cash-flow schedules, accrued interest, price and yield, and
+// duration.  Prices are per 100 of face value and yields are annual rates compounded
+// at the coupon frequency, expressed as decimals.
+package bond
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/nycmonkey/fast_lem"
+)
+
+var (
+	ErrNotABond  = errors.New("not a fixed-income security with a maturity date")
+	ErrMatured   = errors.New("the bond matures on or before the settlement date")
+	ErrNotIssued = errors.New("the bond is issued after the settlement date")
+)
+
+// Bond is a fixed-coupon bond repaying its face value at maturity
+type Bond struct {
+	// Coupon is the annual coupon rate as a percentage of face value
+	Coupon   float64
+	Maturity time.Time
+	// Issued, if known, is when interest starts to accrue
+	Issued time.Time
+	Convention
+}
+
+// New returns the Bond described by s, with the conventions of its issue type
+func New(s *fast_lem.Security) (*Bond, error) {
+	d := s.Description
+	if d.IssueTypeInfo().AssetClass != fast_lem.AssetClassFixedIncome || d.Maturity.IsZero() {
+		return nil, ErrNotABond
+	}
+	b := &Bond{Coupon: d.Coupon, Maturity: d.Maturity, Convention: defaultConvention}
+	if c, ok := conventions[d.Code()]; ok {
+		b.Convention = c
+	}
+	if b.Coupon == 0 {
+		b.Convention = zeroConvention
+	}
+	if len(s.Inception) > 0 {
+		issued, err := time.Parse(fast_lem.FactSetDateFormat, s.Inception)
+		if err != nil {
+			return nil, fmt.Errorf("inception date: %s", err)
+		}
+		b.Issued = issued
+	}
+	return b, nil
+}
+
+// CashFlow is one payment per 100 of face value
+type CashFlow struct {
+	Date time.Time
+	// AccrualStart and AccrualEnd bound the period over which the coupon accrues
+	AccrualStart time.Time
+	AccrualEnd   time.Time
+	Coupon       float64
+	Principal    float64
+}
+
+// Amount is the total paid
+func (cf CashFlow) Amount() float64 {
+	return cf.Coupon + cf.Principal
+}
+
+// couponDates returns the last coupon date on or before settle, or the start of the
+// period if the bond was issued since, and the coupon dates after settle
+func (b *Bond) couponDates(settle time.Time) (prev time.Time, next []time.Time) {
+	eom := endOfMonth(b.Maturity)
+	d := b.Maturity
+	for k := 1; d.After(settle); k++ {
+		next = append(next, d)
+		d = addMonths(b.Maturity, -k*b.Frequency.months(), eom)
+	}
+	for i, j := 0, len(next)-1; i < j; i, j = i+1, j-1 {
+		next[i], next[j] = next[j], next[i]
+	}
+	return d, next
+}
+
+// Schedule returns the payments due after settle, which may not precede Issued
+func (b *Bond) Schedule(settle time.Time) ([]CashFlow, error) {
+	if !b.Maturity.After(settle) {
+		return nil, ErrMatured
+	}
+	if settle.Before(b.Issued) {
+		return nil, ErrNotIssued
+	}
+	if b.Frequency == Zero {
+		return []CashFlow{{Date: b.Maturity, AccrualStart: b.Issued, AccrualEnd: b.Maturity, Principal: 100}}, nil
+	}
+	prev, dates := b.couponDates(settle)
+	flows := make([]CashFlow, len(dates))
+	coupon := b.Coupon / float64(b.Frequency)
+	for i, d := range dates {
+		flows[i] = CashFlow{Date: d, AccrualStart: prev, AccrualEnd: d, Coupon: coupon}
+		prev = d
+	}
+	flows[len(flows)-1].Principal = 100
+	if flows[0].AccrualStart.Before(b.Issued) {
+		flows[0].AccrualStart = b.Issued
+	}
+	return flows, nil
+}
+
+// YearsToMaturity is the time from settle to maturity in years of 365.25 days
+func (b *Bond) YearsToMaturity(settle time.Time) float64 {
+	return days(settle, b.Maturity) / 365.25
+}
+
+// Accrued returns the interest accrued since the last coupon at settle
+func (b *Bond) Accrued(settle time.Time) (float64, error) {
+	flows, err := b.Schedule(settle)
+	if err != nil || b.Frequency == Zero {
+		return 0, err
+	}
+	first := flows[0]
+	periodStart := addMonths(first.Date, -b.Frequency.months(), endOfMonth(b.Maturity))
+	return first.Coupon * b.periodFraction(first.AccrualStart, settle, periodStart, first.Date), nil
+}
+
+// discounted returns the present value at settle of each payment after settle at
+// yield, and the time in years until each
+func (b *Bond) discounted(yield float64, settle time.Time) (pv, years []float64, err error) {
+	flows, err := b.Schedule(settle)
+	if err != nil {
+		return nil, nil, err
+	}
+	if b.Frequency == Zero {
+		// zero-coupon bonds are quoted on a semiannual bond-equivalent basis
+		t := days(settle, b.Maturity) / 365
+		return []float64{100 / math.Pow(1+yield/2, 2*t)}, []float64{t}, nil
+	}
+	f := float64(b.Frequency)
+	periodStart := addMonths(flows[0].Date, -b.Frequency.months(), endOfMonth(b.Maturity))
+	// w is the fraction of a period until the next coupon
+	w := b.periodFraction(settle, flows[0].Date, periodStart, flows[0].Date)
+	pv = make([]float64, len(flows))
+	years = make([]float64, len(flows))
+	for k, cf := range flows {
+		periods := float64(k) + w
+		pv[k] = cf.Amount() / math.Pow(1+yield/f, periods)
+		years[k] = periods / f
+	}
+	return pv, years, nil
+}
+
+// DirtyPrice returns the price including accrued interest at yield
+func (b *Bond) DirtyPrice(yield float64, settle time.Time) (float64, error) {
+	pv, _, err := b.discounted(yield, settle)
+	return sum(pv), err
+}
+
+// Price returns the clean price, excluding accrued interest, at yield
+func (b *Bond) Price(yield float64, settle time.Time) (float64, error) {
+	dirty, err := b.DirtyPrice(yield, settle)
+	if err != nil {
+		return 0, err
+	}
+	accrued, err := b.Accrued(settle)
+	return dirty - accrued, err
+}
+
+// Yield returns the yield to maturity at the clean price
+func (b *Bond) Yield(price float64, settle time.Time) (float64, error) {
+	if price <= 0 {
+		return 0, fmt.Errorf("price %g is not positive", price)
+	}
+	accrued, err := b.Accrued(settle)
+	if err != nil {
+		return 0, err
+	}
+	target := price + accrued
+	compounding := float64(b.Frequency)
+	if b.Frequency == Zero {
+		compounding = 2
+	}
+	// Newton's method, from the coupon rate
+	y := b.Coupon / 100
+	if y == 0 {
+		y = 0.05
+	}
+	for i := 0; i < 100; i++ {
+		pv, years, err := b.discounted(y, settle)
+		if err != nil {
+			return 0, err
+		}
+		var derivative float64
+		for k := range pv {
+			derivative -= years[k] * pv[k] / (1 + y/compounding)
+		}
+		step := (sum(pv) - target) / derivative
+		y -= step
+		if y <= -compounding {
+			y = -compounding / 2
+		}
+		if math.Abs(step) < 1e-12 {
+			return y, nil
+		}
+	}
+	return 0, fmt.Errorf("no yield found for price %g", price)
+}
+
+// Duration returns the Macaulay duration, in years, and the modified duration at
+// yield
+func (b *Bond) Duration(yield float64, settle time.Time) (macaulay, modified float64, err error) {
+	pv, years, err := b.discounted(yield, settle)
+	if err != nil {
+		return 0, 0, err
+	}
+	for k := range pv {
+		macaulay += years[k] * pv[k]
+	}
+	macaulay /= sum(pv)
+	compounding := float64(b.Frequency)
+	if b.Frequency == Zero {
+		compounding = 2
+	}
+	return macaulay, macaulay / (1 + yield/compounding), nil
+}
+
+func sum(values []float64) (total float64) {
+	for _, v := range values {
+		total += v
+	}
+	return
+}
@@ -0,0 +1,178 @@
+package bond
+
+import (
+	"encoding/json"
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nycmonkey/fast_lem"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(fast_lem.FactSetDateFormat, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func near(got, want float64) bool {
+	return math.Abs(got-want) < 1e-6
+}
+
+func TestParBond(t *testing.T) {
+	b, err := New(fast_lem.New("459200AS0", "", "", "", "", "BD", "5", "2031-11-30"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Convention != defaultConvention {
+		t.Errorf("Got %+v, want semiannual 30/360", b.Convention)
+	}
+	settle := date("2021-11-30")
+	flows, err := b.Schedule(settle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(flows) != 20 || !flows[0].Date.Equal(date("2022-05-31")) || flows[19].Amount() != 102.5 {
+		t.Errorf("Got %d flows from %s ending %+v, want 20 from 2022-05-31 ending 102.5", len(flows), flows[0].Date, flows[19])
+	}
+	price, err := b.Price(0.05, settle)
+	if err != nil || !near(price, 100) {
+		t.Errorf("Got price %g, %v, want 100", price, err)
+	}
+	yield, err := b.Yield(100, settle)
+	if err != nil || !near(yield, 0.05) {
+		t.Errorf("Got yield %g, %v, want 0.05", yield, err)
+	}
+	_, modified, err := b.Duration(0.05, settle)
+	if want := (1 - math.Pow(1.025, -20)) / 0.05; err != nil || !near(modified, want) {
+		t.Errorf("Got modified duration %g, %v, want %g", modified, err, want)
+	}
+	// 88 days on 30/360 from the 30th of November to the 28th of February, of 180
+	accrued, err := b.Accrued(date("2022-02-28"))
+	if err != nil || !near(accrued, 2.5*88.0/180) {
+		t.Errorf("Got accrued %g, %v, want %g", accrued, err, 2.5*88.0/180)
+	}
+	for _, p := range []float64{92.25, 100, 107.5} {
+		y, err := b.Yield(p, date("2024-03-15"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := b.Price(y, date("2024-03-15")); !near(got, p) {
+			t.Errorf("Price %g: yield %g prices at %g", p, y, got)
+		}
+	}
+	if _, err = b.Schedule(date("2031-11-30")); err != ErrMatured {
+		t.Errorf("Got %v at maturity, want ErrMatured", err)
+	}
+}
+
+func TestUnissuedBond(t *testing.T) {
+	s := fast_lem.New("459200AU5", "", "", "", "", "BD", "5", "2030-06-15")
+	s.Inception = "2025-06-15"
+	b, err := New(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settle := date("2020-01-10")
+	if _, err = b.Schedule(settle); err != ErrNotIssued {
+		t.Errorf("Got %v for a schedule before issue, want ErrNotIssued", err)
+	}
+	if _, err = b.Accrued(settle); err != ErrNotIssued {
+		t.Errorf("Got %v for accrued interest before issue, want ErrNotIssued", err)
+	}
+	if _, err = b.Price(0.05, settle); err != ErrNotIssued {
+		t.Errorf("Got %v for a price before issue, want ErrNotIssued", err)
+	}
+	flows, err := b.Schedule(date("2025-06-15"))
+	if err != nil || len(flows) != 10 || !flows[0].AccrualStart.Equal(date("2025-06-15")) {
+		t.Errorf("Got %d flows, %v on the issue date, want 10 accruing from 2025-06-15", len(flows), err)
+	}
+}
+
+func TestZeroCouponBond(t *testing.T) {
+	b, err := New(fast_lem.New("912796XX0", "", "", "", "", "US", "", "2025-01-01"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	settle := date("2024-01-01")
+	price, err := b.Price(0.04, settle)
+	years := days(settle, b.Maturity) / 365
+	if want := 100 / math.Pow(1.02, 2*years); err != nil || !near(price, want) {
+		t.Errorf("Got %g, %v, want %g", price, err, want)
+	}
+	if macaulay, _, _ := b.Duration(0.04, settle); !near(macaulay, years) {
+		t.Errorf("Got Macaulay duration %g, want %g", macaulay, years)
+	}
+	if b.Convention != (Convention{Zero, Actual365}) {
+		t.Errorf("Got %+v for a Treasury bill, want zero-coupon actual/365", b.Convention)
+	}
+	note, err := New(fast_lem.New("91282CXX0", "", "", "", "", "US", "1.5", "2025-01-01"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	flows, err := note.Schedule(settle)
+	if note.Convention != (Convention{SemiAnnual, ActualActual}) || err != nil || len(flows) != 2 || flows[0].Coupon != 0.75 {
+		t.Errorf("Got %+v with %d flows, %v for a 1.5%% Treasury note, want two semiannual coupons of 0.75",
+			note.Convention, len(flows), err)
+	}
+	if _, err = New(fast_lem.New("123456789", "", "", "", "", "EQ", "", "")); err != ErrNotABond {
+		t.Errorf("Got %v for an equity, want ErrNotABond", err)
+	}
+}
+
+func TestAnalyticsHandler(t *testing.T) {
+	c := make(chan *fast_lem.Security, 2)
+	c <- fast_lem.New("459200AS0", "US459200AS05", "", "", "", "BD", "5", "2031-11-30")
+	c <- fast_lem.New("459200AT8", "", "", "", "", "EQ", "", "")
+	close(c)
+	m, err := fast_lem.NewSecurityMaster(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := Server{Getter: m}
+	get := func(query string) (int, *Analytics) {
+		w := httptest.NewRecorder()
+		server.AnalyticsHandler(w, httptest.NewRequest("GET", "/analytics?"+query, nil))
+		a := &Analytics{}
+		if w.Code == 200 {
+			if err := json.Unmarshal(w.Body.Bytes(), a); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return w.Code, a
+	}
+	code, a := get("key=459200AS0&settle=2021-11-30&price=100&schedule=true")
+	if code != 200 || a.Yield == nil || !near(*a.Yield, 0.05) || len(a.Schedule) != 20 {
+		t.Errorf("Got %d %+v, want a 5%% yield and 20 cash flows", code, a)
+	}
+	registry, err := fast_lem.NewClientRegistry(&fast_lem.Client{
+		Name:        "isins",
+		Keys:        []string{"secret"},
+		Entitlement: fast_lem.Entitlement{Fields: []string{"ISIN", "Description"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest("GET", "/analytics?key=US459200AS05&settle=2021-11-30&price=100", nil)
+	r.Header.Set("X-API-Key", "secret")
+	w := httptest.NewRecorder()
+	fast_lem.Guard(registry, nil, server.AnalyticsHandler)(w, r)
+	if w.Code != 200 || strings.Contains(w.Body.String(), "459200AS0\"") {
+		t.Errorf("Got %d %s for a client not entitled to CUSIPs, want analytics without the CUSIP", w.Code, w.Body)
+	}
+	for query, want := range map[string]int{
+		"key=459200AT8":                     422,
+		"key=000000000":                     404,
+		"key=459200AS0&settle=2040-01-01":   422,
+		"key=459200AS0&price=100&yield=0.1": 400,
+		"key=459200AS0&settle=2021":         400,
+	} {
+		if code, _ := get(query); code != want {
+			t.Errorf("%s: got %d, want %d", query, code, want)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package bond
+
+import (
+	"fmt"
+	"time"
+)
+
+// Frequency is the number of coupons paid per year
+type Frequency int
+
+const (
+	Zero       Frequency = 0
+	Annual     Frequency = 1
+	SemiAnnual Frequency = 2
+	Quarterly  Frequency = 4
+	Monthly    Frequency = 12
+)
+
+func (f Frequency) String() string {
+	switch f {
+	case Zero:
+		return "zero"
+	case Annual:
+		return "annual"
+	case SemiAnnual:
+		return "semiannual"
+	case Quarterly:
+		return "quarterly"
+	case Monthly:
+		return "monthly"
+	}
+	return fmt.Sprintf("%d per year", int(f))
+}
+
+// MarshalText encodes f as its name
+func (f Frequency) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// UnmarshalText decodes a Frequency from its name
+func (f *Frequency) UnmarshalText(text []byte) error {
+	for _, candidate := range []Frequency{Zero, Annual, SemiAnnual, Quarterly, Monthly} {
+		if candidate.String() == string(text) {
+			*f = candidate
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown coupon frequency %q", text)
+}
+
+// months is the length of a coupon period in months
+func (f Frequency) months() int {
+	return 12 / int(f)
+}
+
+// DayCount is a convention for counting the fraction of a year between two dates
+type DayCount int
+
+const (
+	// Thirty360 is the US 30/360 (bond basis) convention
+	Thirty360 DayCount = iota
+	// ActualActual is the ICMA actual/actual convention, which counts actual days as a
+	// fraction of the actual days in the coupon period
+	ActualActual
+	Actual360
+	Actual365
+)
+
+func (dc DayCount) String() string {
+	switch dc {
+	case Thirty360:
+		return "30/360"
+	case ActualActual:
+		return "ACT/ACT"
+	case Actual360:
+		return "ACT/360"
+	case Actual365:
+		return "ACT/365"
+	}
+	return "unknown"
+}
+
+// MarshalText encodes dc as its name
+func (dc DayCount) MarshalText() ([]byte, error) {
+	return []byte(dc.String()), nil
+}
+
+// UnmarshalText decodes a DayCount from its name
+func (dc *DayCount) UnmarshalText(text []byte) error {
+	for _, candidate := range []DayCount{Thirty360, ActualActual, Actual360, Actual365} {
+		if candidate.String() == string(text) {
+			*dc = candidate
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown day count %q", text)
+}
+
+// Convention is how a bond pays and accrues interest
+type Convention struct {
+	Frequency Frequency
+	DayCount  DayCount
+}
+
+// conventions are the market conventions of the coupon-paying bonds of the
+// fixed-income issue types, keyed by FactSet issue type code.  Fixed-income codes not
+// listed pay semiannually on 30/360.
+var conventions = map[string]Convention{
+	"UL": {SemiAnnual, ActualActual},
+	"US": {SemiAnnual, ActualActual},
+	"MB": {Monthly, Thirty360},
+	"AB": {Monthly, Thirty360},
+	"FM": {Monthly, Thirty360},
+	"LN": {Quarterly, Actual360},
+}
+
+var defaultConvention = Convention{SemiAnnual, Thirty360}
+
+// zeroConvention is the convention of zero-coupon bonds of every issue type, such as
+// Treasury bills, which are priced on a semiannual bond-equivalent basis over
+// actual/365 years
+var zeroConvention = Convention{Zero, Actual365}
+
+// days returns the number of days from start to end
+func days(start, end time.Time) float64 {
+	return end.Sub(start).Hours() / 24
+}
+
+// periodFraction returns the fraction of the coupon period from periodStart to
+// periodEnd that elapses from start to end
+func (c Convention) periodFraction(start, end, periodStart, periodEnd time.Time) float64 {
+	switch c.DayCount {
+	case ActualActual:
+		return days(start, end) / days(periodStart, periodEnd)
+	case Actual360:
+		return days(start, end) / 360 * float64(c.Frequency)
+	case Actual365:
+		return days(start, end) / 365 * float64(c.Frequency)
+	}
+	return thirty360(start, end) / 360 * float64(c.Frequency)
+}
+
+// thirty360 counts the days from start to end with 30-day months
+func thirty360(start, end time.Time) float64 {
+	y1, m1, d1 := start.Date()
+	y2, m2, d2 := end.Date()
+	if d1 == 31 {
+		d1 = 30
+	}
+	if d2 == 31 && d1 == 30 {
+		d2 = 30
+	}
+	return float64(360*(y2-y1) + 30*(int(m2)-int(m1)) + d2 - d1)
+}
+
+// addMonths moves t by n months, keeping to the end of the month if eom is set and
+// otherwise clamping the day to the length of the month reached
+func addMonths(t time.Time, n int, eom bool) time.Time {
+	y, m, d := t.Date()
+	first := time.Date(y, m+time.Month(n), 1, 0, 0, 0, 0, t.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	if eom || d > last {
+		d = last
+	}
+	return first.AddDate(0, 0, d-1)
+}
+
+// endOfMonth reports whether t is the last day of its month
+func endOfMonth(t time.Time) bool {
+	return t.AddDate(0, 0, 1).Day() == 1
+}
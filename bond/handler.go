@@ -0,0 +1,195 @@
+package bond
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/nycmonkey/fast_lem"
+)
+
+// Server answers analytics requests for the bonds a Getter holds
+type Server struct {
+	fast_lem.Getter
+}
+
+// Analytics describes one bond as of a settlement date.  The price, yield and
+// durations are omitted unless a price or yield was given.
+type Analytics struct {
+	Key string
+	// Cusip is omitted for clients not entitled to CUSIPs
+	Cusip           string `json:",omitempty"`
+	Convention      Convention
+	Settlement      string
+	YearsToMaturity float64
+	Accrued         float64
+	CleanPrice      *float64 `json:",omitempty"`
+	DirtyPrice      *float64 `json:",omitempty"`
+	Yield           *float64 `json:",omitempty"`
+	// MacaulayDuration is in years
+	MacaulayDuration *float64            `json:",omitempty"`
+	ModifiedDuration *float64            `json:",omitempty"`
+	Schedule         []ScheduledCashFlow `json:",omitempty"`
+}
+
+// ScheduledCashFlow is the JSON representation of a CashFlow, with dates in
+// fast_lem.FactSetDateFormat
+type ScheduledCashFlow struct {
+	Date         string
+	AccrualStart string `json:",omitempty"`
+	AccrualEnd   string
+	Coupon       float64
+	Principal    float64
+	Amount       float64
+}
+
+func isoDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(fast_lem.FactSetDateFormat)
+}
+
+// AnalyticsHandler responds with the Analytics of the bond identified by the key query
+// parameter, settling on the settle parameter (today by default).  Given a price
+// parameter it computes the yield, or given a yield parameter the price; schedule=true
+// adds the remaining cash flows.  Clients must be entitled to the key's identifier type
+// and to the Description, which holds the coupon and maturity.
+func (s Server) AnalyticsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	key := q.Get("key")
+	settle, err := fast_lem.ParseAsOf(q.Get("settle"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	settle = time.Date(settle.Year(), settle.Month(), settle.Day(), 0, 0, 0, 0, time.UTC)
+	e := fast_lem.EntitlementFrom(r.Context())
+	if !e.AllowsType(fast_lem.IdentifierTypeOf(key)) || (e.RestrictsFields() && !contains(e.Fields, "Description")) {
+		http.Error(w, "Not entitled to analytics for "+key, http.StatusForbidden)
+		return
+	}
+	results, err := fast_lem.Lookup(r.Context(), s.Getter, key)
+	if err == fast_lem.ErrNotLoaded {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	result := results[0]
+	switch result.Status {
+	case fast_lem.Found:
+	case fast_lem.Invalid:
+		http.Error(w, "Invalid key "+strconv.Quote(key)+"; expected a CUSIP, ISIN or SEDOL", http.StatusBadRequest)
+		return
+	case fast_lem.NotFound:
+		http.Error(w, "No security found for "+key, http.StatusNotFound)
+		return
+	default:
+		http.Error(w, result.Err.Error(), http.StatusInternalServerError)
+		return
+	}
+	b, err := New(result.Security)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	a, err := b.analytics(settle, q)
+	if err == ErrMatured || err == ErrNotIssued {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.Key = key
+	if !e.RestrictsFields() || contains(e.Fields, "Cusip") {
+		a.Cusip = result.Security.CUSIP
+	}
+	js, err := json.Marshal(a)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
+
+// analytics computes the Analytics requested by the price, yield and schedule query
+// parameters
+func (b *Bond) analytics(settle time.Time, q url.Values) (*Analytics, error) {
+	flows, err := b.Schedule(settle)
+	if err != nil {
+		return nil, err
+	}
+	a := &Analytics{
+		Convention:      b.Convention,
+		Settlement:      isoDate(settle),
+		YearsToMaturity: b.YearsToMaturity(settle),
+	}
+	if a.Accrued, err = b.Accrued(settle); err != nil {
+		return nil, err
+	}
+	var yield float64
+	switch price, y := q.Get("price"), q.Get("yield"); {
+	case len(price) > 0 && len(y) > 0:
+		return nil, errors.New("give a price or a yield, not both")
+	case len(price) > 0:
+		p, err := strconv.ParseFloat(price, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid price %q", price)
+		}
+		if yield, err = b.Yield(p, settle); err != nil {
+			return nil, err
+		}
+	case len(y) > 0:
+		if yield, err = strconv.ParseFloat(y, 64); err != nil {
+			return nil, fmt.Errorf("invalid yield %q", y)
+		}
+	default:
+		yield = math.NaN()
+	}
+	if !math.IsNaN(yield) {
+		clean, err := b.Price(yield, settle)
+		if err != nil {
+			return nil, err
+		}
+		dirty := clean + a.Accrued
+		macaulay, modified, err := b.Duration(yield, settle)
+		if err != nil {
+			return nil, err
+		}
+		a.CleanPrice, a.DirtyPrice, a.Yield = &clean, &dirty, &yield
+		a.MacaulayDuration, a.ModifiedDuration = &macaulay, &modified
+	}
+	if q.Get("schedule") == "true" {
+		for _, cf := range flows {
+			a.Schedule = append(a.Schedule, ScheduledCashFlow{
+				Date:         isoDate(cf.Date),
+				AccrualStart: isoDate(cf.AccrualStart),
+				AccrualEnd:   isoDate(cf.AccrualEnd),
+				Coupon:       cf.Coupon,
+				Principal:    cf.Principal,
+				Amount:       cf.Amount(),
+			})
+		}
+	}
+	return a, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -1,7 +1,46 @@
 package fast_lem
 
 const (
-	DetailsBucket = `DetailsByCusip`
-	IsinBucket    = `CUSIPByISIN`
-	SedolBucket   = `CUSIPBySEDOL`
+	DetailsBucket  = `DetailsByCusip`
+	IsinBucket     = `CUSIPByISIN`
+	SedolBucket    = `CUSIPBySEDOL`
+	MetadataBucket = `Metadata`
+	// EntityBucket maps each FactSet entity ID to its Entity, JSON encoded
+	EntityBucket = `EntitiesByID`
+	// EntityChildrenBucket holds an empty value under the key parent\x00child for each
+	// entity with a parent
+	EntityChildrenBucket = `EntitiesByParent`
 )
+
+// Secondary indexes, each holding an empty value under the key value\x00CUSIP so that a
+// prefix scan lists the CUSIPs with that value in ascending order
+const (
+	IssueTypeIndexBucket = `CUSIPByIssueType`
+	CountryIndexBucket   = `CUSIPByCountry`
+	CurrencyIndexBucket  = `CUSIPByCurrency`
+	// MaturityIndexBucket is keyed by the maturity date in FactSetDateFormat
+	MaturityIndexBucket = `CUSIPByMaturity`
+	EntityIndexBucket   = `CUSIPByEntity`
+)
+
+// indexBuckets are the secondary indexes, with the value each indexes
+var indexBuckets = []struct {
+	name  string
+	value func(s *Security) string
+}{
+	{IssueTypeIndexBucket, func(s *Security) string { return s.Description.Code() }},
+	{CountryIndexBucket, func(s *Security) string { return s.Country }},
+	{CurrencyIndexBucket, func(s *Security) string { return s.Currency }},
+	{EntityIndexBucket, func(s *Security) string { return s.LegalEntityID }},
+	{MaturityIndexBucket, func(s *Security) string {
+		if s.Description.Maturity.IsZero() {
+			return ""
+		}
+		return s.Description.Maturity.Format(FactSetDateFormat)
+	}},
+}
+
+// indexKey is the key under which a secondary index records that cusip has value
+func indexKey(value, cusip string) []byte {
+	return []byte(value + "\x00" + cusip)
+}
@@ -0,0 +1,263 @@
+package fast_lem
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pquerna/ffjson/ffjson"
+)
+
+// Cache is a Getter that keeps the most recently requested Securities in memory in
+// front of another Getter.  Concurrent misses on the same key are coalesced into a
+// single lookup.  Securities returned by a Cache are shared and must not be modified.
+type Cache struct {
+	// counters come first to keep them 64-bit aligned for sync/atomic
+	hits      uint64
+	misses    uint64
+	coalesced uint64
+	evictions uint64
+
+	next Getter
+	size int
+
+	mu         sync.Mutex
+	ll         *list.List
+	items      map[string]*list.Element
+	calls      map[string]*cacheCall
+	generation uint64
+}
+
+type cacheEntry struct {
+	key string
+	// s is nil for keys that were not found
+	s *Security
+}
+
+// cacheCall is an in-flight lookup of one key that other requests can wait for
+type cacheCall struct {
+	done   chan struct{}
+	result Result
+	err    error
+}
+
+// CacheStats counts the lookups served by a Cache
+type CacheStats struct {
+	Entries   int
+	Capacity  int
+	Hits      uint64
+	Misses    uint64
+	Coalesced uint64
+	Evictions uint64
+}
+
+// NewCache returns a Cache of up to size Securities in front of next
+func NewCache(next Getter, size int) *Cache {
+	return &Cache{
+		next:  next,
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+		calls: make(map[string]*cacheCall),
+	}
+}
+
+// Purge empties the cache.  Lookups already in flight are not added to it.
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+	c.generation++
+	c.mu.Unlock()
+}
+
+// Stats returns the cache's counters
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	entries := c.ll.Len()
+	c.mu.Unlock()
+	return CacheStats{
+		Entries:   entries,
+		Capacity:  c.size,
+		Hits:      atomic.LoadUint64(&c.hits),
+		Misses:    atomic.LoadUint64(&c.misses),
+		Coalesced: atomic.LoadUint64(&c.coalesced),
+		Evictions: atomic.LoadUint64(&c.evictions),
+	}
+}
+
+// RegisterMetrics exposes the cache's counters through m
+func (c *Cache) RegisterMetrics(m *Metrics) {
+	m.GaugeFunc("lem_cache_entries", "Securities held by the lookup cache.",
+		func() float64 { return float64(c.Stats().Entries) })
+	m.GaugeFunc("lem_cache_capacity", "Maximum number of securities held by the lookup cache.",
+		func() float64 { return float64(c.size) })
+	m.CounterFunc("lem_cache_hits_total", "Keys resolved from the lookup cache.",
+		func() float64 { return float64(atomic.LoadUint64(&c.hits)) })
+	m.CounterFunc("lem_cache_misses_total", "Keys not found in the lookup cache.",
+		func() float64 { return float64(atomic.LoadUint64(&c.misses)) })
+	m.CounterFunc("lem_cache_coalesced_total", "Cache misses that waited for a lookup already in flight.",
+		func() float64 { return float64(atomic.LoadUint64(&c.coalesced)) })
+	m.CounterFunc("lem_cache_evictions_total", "Securities evicted from the lookup cache.",
+		func() float64 { return float64(atomic.LoadUint64(&c.evictions)) })
+}
+
+// StatsHandler responds with the cache's counters as JSON
+func (c *Cache) StatsHandler(w http.ResponseWriter, r *http.Request) {
+	stats := c.Stats()
+	js, err := ffjson.Marshal(&stats)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
+
+// Get "hydrates" security details from one or more identifiers
+func (c *Cache) Get(keys ...string) ([]*Security, error) {
+	return c.GetContext(context.Background(), keys...)
+}
+
+// GetContext "hydrates" security details from one or more identifiers, giving up if
+// ctx is done first
+func (c *Cache) GetContext(ctx context.Context, keys ...string) ([]*Security, error) {
+	results, err := c.Lookup(ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+	return Securities(results)
+}
+
+// Lookup resolves each key from the cache where possible, and the rest with a single
+// batch lookup against the underlying Getter
+func (c *Cache) Lookup(ctx context.Context, keys ...string) ([]Result, error) {
+	results := make([]Result, len(keys))
+	var owned []string
+	var ownedAt []int
+	waiting := make(map[int]*cacheCall)
+	c.mu.Lock()
+	generation := c.generation
+	for i, k := range keys {
+		t := IdentifierTypeOf(k)
+		if t == InvalidIdentifier {
+			results[i] = Result{Key: k, Type: t, Status: Invalid}
+			continue
+		}
+		if e, ok := c.items[k]; ok {
+			c.ll.MoveToFront(e)
+			results[i] = newResult(k, t, e.Value.(*cacheEntry).s, nil)
+			atomic.AddUint64(&c.hits, 1)
+			continue
+		}
+		atomic.AddUint64(&c.misses, 1)
+		if call, ok := c.calls[k]; ok {
+			waiting[i] = call
+			atomic.AddUint64(&c.coalesced, 1)
+			continue
+		}
+		c.calls[k] = &cacheCall{done: make(chan struct{})}
+		owned = append(owned, k)
+		ownedAt = append(ownedAt, i)
+	}
+	c.mu.Unlock()
+
+	if len(owned) > 0 {
+		resolved, err := Lookup(ctx, c.next, owned...)
+		c.mu.Lock()
+		for j, k := range owned {
+			call := c.calls[k]
+			delete(c.calls, k)
+			if err != nil {
+				call.err = err
+			} else {
+				call.result = resolved[j]
+				if resolved[j].Status != Failed && generation == c.generation {
+					c.add(k, resolved[j].Security)
+				}
+			}
+			close(call.done)
+		}
+		c.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range ownedAt {
+			results[i] = resolved[j]
+		}
+	}
+
+	for i, call := range waiting {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-call.done:
+		}
+		if call.err != nil {
+			// the request that owned the lookup gave up; resolve the key ourselves
+			r, err := Lookup(ctx, c.next, keys[i])
+			if err != nil {
+				return nil, err
+			}
+			results[i] = r[0]
+			continue
+		}
+		results[i] = call.result
+		results[i].Key = keys[i]
+	}
+	return results, nil
+}
+
+// add caches s under key, evicting the least recently used entry if the cache is
+// full.  c.mu must be held.
+func (c *Cache) add(key string, s *Security) {
+	if c.size <= 0 {
+		return
+	}
+	if e, ok := c.items[key]; ok {
+		c.ll.MoveToFront(e)
+		e.Value.(*cacheEntry).s = s
+		return
+	}
+	c.items[key] = c.ll.PushFront(&cacheEntry{key: key, s: s})
+	for c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).key)
+		atomic.AddUint64(&c.evictions, 1)
+	}
+}
+
+// Describe returns the provenance of the data behind the cache
+func (c *Cache) Describe() (*Metadata, error) {
+	if d, ok := c.next.(Describer); ok {
+		return d.Describe()
+	}
+	return nil, ErrNoMetadata
+}
+
+// Search passes f to the storage behind the cache; search results are not cached
+func (c *Cache) Search(ctx context.Context, f *Filter) (*SearchResponse, error) {
+	return Search(ctx, c.next, f)
+}
+
+// ParentChain returns the parent chain of the entity with id from the storage behind
+// the cache
+func (c *Cache) ParentChain(ctx context.Context, id string) ([]Entity, error) {
+	return ParentChain(ctx, c.next, id)
+}
+
+// Descendants returns id and every entity beneath it from the storage behind the cache
+func (c *Cache) Descendants(ctx context.Context, id string) ([]string, error) {
+	return Descendants(ctx, c.next, id)
+}
+
+// RecordCounts counts the records behind the cache
+func (c *Cache) RecordCounts() (map[string]int, error) {
+	if rc, ok := c.next.(RecordCounter); ok {
+		return rc.RecordCounts()
+	}
+	return nil, nil
+}
@@ -0,0 +1,82 @@
+package fast_lem
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingGetter counts the keys it is asked for, optionally pausing first
+type countingGetter struct {
+	mapGetter
+	keys  int64
+	pause time.Duration
+}
+
+func (cg *countingGetter) Get(keys ...string) ([]*Security, error) {
+	atomic.AddInt64(&cg.keys, int64(len(keys)))
+	time.Sleep(cg.pause)
+	return cg.mapGetter.Get(keys...)
+}
+
+func TestCache(t *testing.T) {
+	s := testSecurities()
+	next := &countingGetter{mapGetter: mapGetter{s[0].CUSIP: s[0], s[1].CUSIP: s[1], s[2].CUSIP: s[2]}}
+	c := NewCache(next, 2)
+	c.Get(s[0].CUSIP, s[1].CUSIP, "NOPE00000")
+	response, err := c.Get(s[1].CUSIP, "NOPE00000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response[0] != s[1] || len(response[1].CUSIP) != 0 {
+		t.Errorf("Got %+v, want %s and not found", response, s[1].CUSIP)
+	}
+	stats := c.Stats()
+	if stats.Hits != 2 || stats.Misses != 3 || stats.Evictions != 1 || stats.Entries != 2 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	if next.keys != 3 {
+		t.Errorf("Underlying getter saw %d keys, want 3", next.keys)
+	}
+	c.Purge()
+	c.Get(s[1].CUSIP)
+	if next.keys != 4 {
+		t.Errorf("Underlying getter saw %d keys after purge, want 4", next.keys)
+	}
+}
+
+func TestCacheCoalescesMisses(t *testing.T) {
+	s := testSecurities()
+	next := &countingGetter{mapGetter: mapGetter{s[0].CUSIP: s[0]}, pause: 50 * time.Millisecond}
+	c := NewCache(next, 10)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results, err := c.Lookup(context.Background(), s[0].CUSIP)
+			if err != nil || results[0].Security != s[0] {
+				t.Errorf("Got %+v, %v", results, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if next.keys != 1 {
+		t.Errorf("Underlying getter saw %d keys, want 1", next.keys)
+	}
+}
+
+func TestSwappablePurgesCache(t *testing.T) {
+	s := testSecurities()
+	sw := NewSwappable(mapGetter{s[0].CUSIP: s[0]})
+	c := NewCache(sw, 10)
+	sw.OnSwap(c.Purge)
+	c.Get(s[0].CUSIP)
+	sw.Swap(mapGetter{})
+	response, _ := c.Get(s[0].CUSIP)
+	if len(response[0].CUSIP) != 0 {
+		t.Errorf("Got %s from the cache after the swap, want not found", response[0].CUSIP)
+	}
+}
@@ -0,0 +1,219 @@
+package fast_lem
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sort"
+)
+
+// Expectation asserts the field values of the Security found by Key.  Fields are
+// named as for Security.Field.
+type Expectation struct {
+	Key    string
+	Fields map[string]string
+}
+
+// CheckSuite is a declarative set of data-quality assertions about a load
+type CheckSuite struct {
+	// MinRows is the minimum number of securities loaded
+	MinRows int
+	Expect  []Expectation
+	// MinCounts maps issue type codes to the minimum number of securities of that type
+	MinCounts map[string]int
+	// MaxChangePercent bounds the change in loaded rows relative to the previous
+	// load.  Zero disables the check.
+	MaxChangePercent float64
+	// MaxInvalidCICPercent bounds the percentage of loaded rows with an invalid CIC.
+	// Zero disables the check.
+	MaxInvalidCICPercent float64
+}
+
+// DefaultChecks is the suite used when no other is configured.  It makes no
+// assumptions about the feed; etl/checks.example.json is a starting point for a suite
+// that does.
+var DefaultChecks = &CheckSuite{
+	MinRows: 1,
+}
+
+// LoadCheckSuite reads a JSON-encoded CheckSuite from path
+func LoadCheckSuite(path string) (cs *CheckSuite, err error) {
+	var data []byte
+	data, err = ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	cs = &CheckSuite{}
+	err = json.Unmarshal(data, cs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return
+}
+
+// CheckReport lists the outcome of every check in a suite
+type CheckReport struct {
+	Passed   []string
+	Failures []string
+}
+
+func (r *CheckReport) pass(format string, args ...interface{}) {
+	r.Passed = append(r.Passed, fmt.Sprintf(format, args...))
+}
+
+func (r *CheckReport) fail(format string, args ...interface{}) {
+	r.Failures = append(r.Failures, fmt.Sprintf(format, args...))
+}
+
+// OK reports whether every check passed
+func (r *CheckReport) OK() bool {
+	return len(r.Failures) == 0
+}
+
+func (r *CheckReport) String() string {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "%d checks passed, %d failed\n", len(r.Passed), len(r.Failures))
+	for _, f := range r.Failures {
+		fmt.Fprintln(buf, "FAIL:", f)
+	}
+	for _, p := range r.Passed {
+		fmt.Fprintln(buf, "ok:  ", p)
+	}
+	return buf.String()
+}
+
+// Error returns the report as an error if any check failed, nil otherwise
+func (r *CheckReport) Error() error {
+	if r.OK() {
+		return nil
+	}
+	return fmt.Errorf("data-quality checks failed:\n%s", r)
+}
+
+// Check evaluates the suite against g, using g's load metadata if it is a Describer
+func (cs *CheckSuite) Check(g Getter) *CheckReport {
+	var m *Metadata
+	if d, ok := g.(Describer); ok {
+		m, _ = d.Describe()
+	}
+	return cs.Run(g, m)
+}
+
+// Run evaluates the suite against g.  m describes the load and may be nil, in
+// which case count and change checks fail, except that rows are counted with g if it
+// is a RecordCounter.
+func (cs *CheckSuite) Run(g Getter, m *Metadata) (r *CheckReport) {
+	r = &CheckReport{}
+	if cs.MinRows > 0 {
+		rows, err := loadedRows(g, m)
+		switch {
+		case err != nil:
+			r.fail("at least %d rows: %s", cs.MinRows, err)
+		case rows < cs.MinRows:
+			r.fail("at least %d rows: found %d", cs.MinRows, rows)
+		default:
+			r.pass("at least %d rows: found %d", cs.MinRows, rows)
+		}
+	}
+	for _, e := range cs.Expect {
+		cs.runExpectation(r, g, e)
+	}
+	codes := make([]string, 0, len(cs.MinCounts))
+	for code := range cs.MinCounts {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		min := cs.MinCounts[code]
+		if m == nil {
+			r.fail("at least %d %s securities: load metadata unavailable", min, code)
+			continue
+		}
+		if n := m.Counts[code]; n < min {
+			r.fail("at least %d %s securities: found %d", min, code, n)
+		} else {
+			r.pass("at least %d %s securities: found %d", min, code, n)
+		}
+	}
+	if cs.MaxChangePercent > 0 {
+		switch {
+		case m == nil:
+			r.fail("row count within %g%% of previous load: load metadata unavailable", cs.MaxChangePercent)
+		case m.PreviousRows == 0:
+			r.pass("row count within %g%% of previous load: no previous load", cs.MaxChangePercent)
+		default:
+			change := 100 * float64(m.Rows-m.PreviousRows) / float64(m.PreviousRows)
+			if math.Abs(change) > cs.MaxChangePercent {
+				r.fail("row count within %g%% of previous load: %d rows vs %d (%+.2f%%)", cs.MaxChangePercent, m.Rows, m.PreviousRows, change)
+			} else {
+				r.pass("row count within %g%% of previous load: %d rows vs %d (%+.2f%%)", cs.MaxChangePercent, m.Rows, m.PreviousRows, change)
+			}
+		}
+	}
+	if cs.MaxInvalidCICPercent > 0 {
+		switch {
+		case m == nil:
+			r.fail("at most %g%% invalid CICs: load metadata unavailable", cs.MaxInvalidCICPercent)
+		case m.Rows == 0:
+			r.pass("at most %g%% invalid CICs: no rows", cs.MaxInvalidCICPercent)
+		default:
+			invalid := 100 * float64(m.InvalidCICs) / float64(m.Rows)
+			if invalid > cs.MaxInvalidCICPercent {
+				r.fail("at most %g%% invalid CICs: %d of %d rows (%.2f%%)", cs.MaxInvalidCICPercent, m.InvalidCICs, m.Rows, invalid)
+			} else {
+				r.pass("at most %g%% invalid CICs: %d of %d rows (%.2f%%)", cs.MaxInvalidCICPercent, m.InvalidCICs, m.Rows, invalid)
+			}
+		}
+	}
+	return
+}
+
+// loadedRows returns the number of securities loaded according to m, or counted by g
+// if m is nil
+func loadedRows(g Getter, m *Metadata) (int, error) {
+	if m != nil {
+		return m.Rows, nil
+	}
+	if rc, ok := g.(RecordCounter); ok {
+		counts, err := rc.RecordCounts()
+		if err != nil {
+			return 0, err
+		}
+		if n, ok := counts[DetailsBucket]; ok {
+			return n, nil
+		}
+	}
+	return 0, ErrNoMetadata
+}
+
+func (cs *CheckSuite) runExpectation(r *CheckReport, g Getter, e Expectation) {
+	response, err := g.Get(e.Key)
+	if err != nil {
+		r.fail("%s: %s", e.Key, err)
+		return
+	}
+	if len(response) < 1 || len(response[0].CUSIP) == 0 {
+		r.fail("%s: not found", e.Key)
+		return
+	}
+	s := response[0]
+	fields := make([]string, 0, len(e.Fields))
+	for f := range e.Fields {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	for _, f := range fields {
+		want := e.Fields[f]
+		got, ok := s.Field(f)
+		switch {
+		case !ok:
+			r.fail("%s: unknown field %q", e.Key, f)
+		case got != want:
+			r.fail("%s: %s is %q, want %q", e.Key, f, got, want)
+		default:
+			r.pass("%s: %s is %q", e.Key, f, got)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package fast_lem
+
+import "testing"
+
+type mapGetter map[string]*Security
+
+func (m mapGetter) Get(keys ...string) ([]*Security, error) {
+	response := make([]*Security, len(keys))
+	for i, k := range keys {
+		s, ok := m[k]
+		if !ok {
+			s = &Security{}
+		}
+		response[i] = s
+	}
+	return response, nil
+}
+
+func TestCheckSuiteRun(t *testing.T) {
+	g := mapGetter{
+		"US00037NMH60": New("00037NMH6", "US00037NMH60", "", "", "06L3Q8-E", "MU", "5", "2027-07-01"),
+	}
+	cs := &CheckSuite{
+		Expect: []Expectation{
+			{Key: "US00037NMH60", Fields: map[string]string{"LegalEntityId": "06L3Q8-E", "IssueType": "MU", "Coupon": "5"}},
+			{Key: "US00037NMH60", Fields: map[string]string{"Maturity": "2027-07-02"}},
+			{Key: "XX0000000000"},
+		},
+		MinCounts:        map[string]int{"MU": 10, "EQ": 5},
+		MaxChangePercent: 10,
+	}
+	m := &Metadata{Rows: 89, PreviousRows: 100, Counts: map[string]int{"MU": 10}}
+	r := cs.Run(g, m)
+	if len(r.Passed) != 4 {
+		t.Errorf("Got %d passed checks, want 4:\n%s", len(r.Passed), r)
+	}
+	if len(r.Failures) != 4 {
+		t.Errorf("Got %d failed checks, want 4:\n%s", len(r.Failures), r)
+	}
+	if r.Error() == nil {
+		t.Error("Expected an error from a failing report")
+	}
+	if r = DefaultChecks.Run(g, &Metadata{Rows: 1}); !r.OK() {
+		t.Errorf("Default checks failed:\n%s", r)
+	}
+	if r = DefaultChecks.Run(g, &Metadata{}); r.OK() {
+		t.Errorf("Default checks passed an empty load:\n%s", r)
+	}
+	if r = DefaultChecks.Run(testMaster(t), nil); !r.OK() {
+		t.Errorf("Default checks failed against a master without metadata:\n%s", r)
+	}
+}
+
+func TestExampleChecks(t *testing.T) {
+	cs, err := LoadCheckSuite("etl/checks.example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := mapGetter{
+		"US00037NMH60": New("00037NMH6", "US00037NMH60", "", "", "06L3Q8-E", "MU", "5", "2027-07-01"),
+	}
+	if r := cs.Run(g, &Metadata{Rows: 1000000}); !r.OK() {
+		t.Errorf("Example checks failed:\n%s", r)
+	}
+}
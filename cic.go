@@ -0,0 +1,270 @@
+package fast_lem
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// CIC is a Solvency II Complementary Identification Code, such as US14: the ISO code of
+// the country where the asset is quoted (or XL if it is not listed, XT if it is not
+// exchange tradable), then a category and a sub-category.  It marshals to JSON as a
+// DecodedCIC.
+type CIC string
+
+// DecodedCIC describes the parts of a CIC
+type DecodedCIC struct {
+	Code string
+	// QuotationCountry is the ISO country code, XL or XT
+	QuotationCountry string `json:",omitempty"`
+	QuotationLabel   string `json:",omitempty"`
+	Category         string `json:",omitempty"`
+	CategoryLabel    string `json:",omitempty"`
+	SubCategory      string `json:",omitempty"`
+	SubCategoryLabel string `json:",omitempty"`
+	// Error explains why the code could not be decoded
+	Error string `json:",omitempty"`
+}
+
+type cicCategory struct {
+	label string
+	sub   map[byte]string
+}
+
+// risks are the sub-categories of structured notes and collateralised securities
+var risks = map[byte]string{
+	'1': "Equity risk",
+	'2': "Interest rate risk",
+	'3': "Currency risk",
+	'4': "Credit risk",
+	'5': "Real estate risk",
+	'6': "Commodity risk",
+	'7': "Catastrophe and weather risk",
+	'8': "Mortality risk",
+	'9': "Other",
+}
+
+var options = map[byte]string{
+	'1': "Equity and index options",
+	'2': "Bond options",
+	'3': "Currency options",
+	'4': "Warrants",
+	'5': "Commodity options",
+	'6': "Swaptions",
+	'7': "Catastrophe and weather risk",
+	'8': "Mortality risk",
+	'9': "Other",
+}
+
+// cicCategories is the EIOPA CIC table
+var cicCategories = map[byte]cicCategory{
+	'1': {"Government bonds", map[byte]string{
+		'1': "Central Government bonds",
+		'2': "Supra-national bonds",
+		'3': "Regional government bonds",
+		'4': "Local authorities bonds",
+		'5': "Treasury bonds",
+		'6': "Covered bonds",
+		'9': "Other",
+	}},
+	'2': {"Corporate bonds", map[byte]string{
+		'1': "Corporate bonds",
+		'2': "Convertible bonds",
+		'3': "Commercial paper",
+		'4': "Money market instruments",
+		'5': "Hybrid bonds",
+		'6': "Common covered bonds",
+		'7': "Covered bonds subject to specific law",
+		'8': "Subordinated bonds",
+		'9': "Other",
+	}},
+	'3': {"Equity", map[byte]string{
+		'1': "Common equity",
+		'2': "Equity of real estate related corporation",
+		'3': "Equity rights",
+		'4': "Preferred equity",
+		'9': "Other",
+	}},
+	'4': {"Collective Investment Undertakings", map[byte]string{
+		'1': "Equity funds",
+		'2': "Debt funds",
+		'3': "Money market funds",
+		'4': "Asset allocation funds",
+		'5': "Real estate funds",
+		'6': "Alternative funds",
+		'7': "Private equity funds",
+		'8': "Infrastructure funds",
+		'9': "Other",
+	}},
+	'5': {"Structured notes", risks},
+	'6': {"Collateralised securities", risks},
+	'7': {"Cash and deposits", map[byte]string{
+		'1': "Cash",
+		'2': "Transferable deposits (cash equivalents)",
+		'3': "Other deposits short term (less than or equal to one year)",
+		'4': "Other deposits with term longer than one year",
+		'5': "Deposits to cedants",
+		'9': "Other",
+	}},
+	'8': {"Mortgages and loans", map[byte]string{
+		'1': "Uncollateralised loans made",
+		'2': "Loans made collateralised with securities",
+		'4': "Mortgages",
+		'5': "Other collateralised loans made",
+		'6': "Loans on policies",
+		'9': "Other",
+	}},
+	'9': {"Property", map[byte]string{
+		'1': "Property (office and commercial)",
+		'2': "Property (residential)",
+		'3': "Property (for own use)",
+		'4': "Property (under construction for investment)",
+		'5': "Property (under construction for own use)",
+		'6': "Plant and equipment (for own use)",
+		'9': "Other",
+	}},
+	'A': {"Futures", map[byte]string{
+		'1': "Equity and index futures",
+		'2': "Interest rate futures",
+		'3': "Currency futures",
+		'5': "Commodity futures",
+		'7': "Catastrophe and weather risk",
+		'8': "Mortality risk",
+		'9': "Other",
+	}},
+	'B': {"Call Options", options},
+	'C': {"Put Options", options},
+	'D': {"Swaps", map[byte]string{
+		'1': "Interest rate swaps",
+		'2': "Currency swaps",
+		'3': "Interest rate and currency swaps",
+		'4': "Total return swaps",
+		'5': "Security swaps",
+		'7': "Commodity swaps",
+		'8': "Mortality swaps",
+		'9': "Other",
+	}},
+	'E': {"Forwards", map[byte]string{
+		'1': "Forward interest rate agreement",
+		'2': "Forward exchange rate agreement",
+		'9': "Other",
+	}},
+	'F': {"Credit derivatives", map[byte]string{
+		'1': "Credit default swap",
+		'2': "Credit spread option",
+		'3': "Credit spread swap",
+		'4': "Total return swap",
+		'9': "Other",
+	}},
+}
+
+// Decode splits c into its parts, reporting an error if it is malformed or its
+// category or sub-category is not in the CIC table
+func (c CIC) Decode() (*DecodedCIC, error) {
+	d := &DecodedCIC{Code: string(c)}
+	if len(c) != 4 {
+		return d, fmt.Errorf("CIC %q: want 4 characters", string(c))
+	}
+	country := string(c[:2])
+	if country[0] < 'A' || country[0] > 'Z' || country[1] < 'A' || country[1] > 'Z' {
+		return d, fmt.Errorf("CIC %q: %q is not an ISO country code", string(c), country)
+	}
+	category, ok := cicCategories[c[2]]
+	if !ok {
+		return d, fmt.Errorf("CIC %q: unknown category %q", string(c), c[2:3])
+	}
+	sub, ok := category.sub[c[3]]
+	if !ok {
+		return d, fmt.Errorf("CIC %q: unknown %s sub-category %q", string(c), category.label, c[3:])
+	}
+	d.QuotationCountry = country
+	switch country {
+	case "XL":
+		d.QuotationLabel = "Not listed in a stock exchange"
+	case "XT":
+		d.QuotationLabel = "Not exchange tradable"
+	}
+	d.Category, d.CategoryLabel = string(c[2:3]), category.label
+	d.SubCategory, d.SubCategoryLabel = string(c[2:4]), sub
+	return d, nil
+}
+
+// MarshalJSON encodes c as a DecodedCIC
+func (c CIC) MarshalJSON() ([]byte, error) {
+	d, err := c.Decode()
+	if err != nil {
+		d.Error = err.Error()
+	}
+	return json.Marshal(d)
+}
+
+// UnmarshalJSON decodes c from a DecodedCIC or a bare code
+func (c *CIC) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '{' {
+		d := &DecodedCIC{}
+		if err := json.Unmarshal(data, d); err != nil {
+			return err
+		}
+		*c = CIC(d.Code)
+		return nil
+	}
+	var code string
+	if err := json.Unmarshal(data, &code); err != nil {
+		return err
+	}
+	*c = CIC(code)
+	return nil
+}
+
+// cicCategoriesByIssueType are the CIC categories consistent with each issue type
+// code.  Issue types not listed are checked by asset class.
+var cicCategoriesByIssueType = map[string]string{
+	"MU": "1",
+	"UL": "1",
+	"US": "1",
+	"AG": "12",
+	"BC": "2",
+	"AB": "26",
+	"MB": "26",
+	"FM": "268",
+	"LN": "8",
+	"CP": "23",
+	"PQ": "34",
+	"AI": "4",
+	"CA": "7",
+	"FU": "A",
+	"OP": "BC",
+	"WT": "BC",
+	"DR": "ABCDEF",
+}
+
+var cicCategoriesByAssetClass = map[AssetClass]string{
+	AssetClassEquity:      "3",
+	AssetClassFixedIncome: "1256",
+	AssetClassFund:        "4",
+	AssetClassCash:        "7",
+	AssetClassDerivative:  "ABCDEF",
+}
+
+// ValidateCIC reports whether the CIC of s decodes and its category is consistent
+// with the issue type of s.  Securities with no CIC, or of an issue type with no
+// known categories, are only checked for a well-formed code.
+func (s *Security) ValidateCIC() error {
+	if len(s.CIC) == 0 {
+		return nil
+	}
+	d, err := s.CIC.Decode()
+	if err != nil {
+		return err
+	}
+	code := s.Description.Code()
+	allowed, ok := cicCategoriesByIssueType[code]
+	if !ok {
+		allowed, ok = cicCategoriesByAssetClass[s.Description.IssueTypeInfo().AssetClass]
+	}
+	if ok && !strings.Contains(allowed, d.Category) {
+		return fmt.Errorf("CIC %s (%s) is inconsistent with issue type %s (%s)", s.CIC, d.CategoryLabel, code,
+			s.Description.IssueTypeInfo().Label)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package fast_lem
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCICDecode(t *testing.T) {
+	d, err := CIC("US14").Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DecodedCIC{Code: "US14", QuotationCountry: "US", Category: "1", CategoryLabel: "Government bonds",
+		SubCategory: "14", SubCategoryLabel: "Local authorities bonds"}
+	if *d != want {
+		t.Errorf("Got %+v, want %+v", d, want)
+	}
+	if d, _ = CIC("XTB4").Decode(); d.QuotationLabel != "Not exchange tradable" || d.SubCategoryLabel != "Warrants" {
+		t.Errorf("Got %+v, want an untradable warrant", d)
+	}
+	for _, bad := range []string{"", "US1", "us14", "USG1", "US10", "US8X"} {
+		if _, err := CIC(bad).Decode(); err == nil {
+			t.Errorf("Decoded %q", bad)
+		}
+	}
+}
+
+func TestValidateCIC(t *testing.T) {
+	for _, c := range []struct {
+		issueType, cic string
+		valid          bool
+	}{
+		{"MU", "US14", true},
+		{"LN", "US81", true},
+		{"BD", "GB21", true},
+		{"EQ", "XL31", true},
+		{"EQ", "US21", false},
+		{"MU", "US81", false},
+		{"ET", "DE41", true},
+		{"BD", "", true},
+		{"QQ", "US99", true},
+		{"QQ", "US09", false},
+	} {
+		s := New("123456789", "", "", "", "", c.issueType, "", "")
+		s.CIC = CIC(c.cic)
+		if err := s.ValidateCIC(); (err == nil) != c.valid {
+			t.Errorf("%s %s: got %v, want valid %t", c.issueType, c.cic, err, c.valid)
+		}
+	}
+}
+
+func TestCICJSON(t *testing.T) {
+	s := New("123456789", "", "", "", "", "MU", "", "")
+	s.CIC = "US14"
+	js, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(js), `"CIC":{"Code":"US14","QuotationCountry":"US","Category":"1"`) {
+		t.Errorf("Got %s, want the decoded CIC", js)
+	}
+	got := &Security{}
+	if err = json.Unmarshal(js, got); err != nil || got.CIC != s.CIC {
+		t.Errorf("Got %q, %v, want US14", got.CIC, err)
+	}
+	f := &Filter{CICCategories: []string{"1"}, CICQuotationCountries: []string{"US"}}
+	if !f.Match(s, s.Description.Maturity) {
+		t.Error("Filter by CIC category and country did not match")
+	}
+	if f.CICCategories = []string{"13"}; f.Match(s, s.Description.Maturity) {
+		t.Error("Filter by another CIC sub-category matched")
+	}
+}
+
+func TestInvalidCICCheck(t *testing.T) {
+	cs := &CheckSuite{MaxInvalidCICPercent: 1}
+	for invalid, ok := range map[int]bool{0: true, 1: true, 2: false} {
+		if r := cs.Run(testMaster(t), &Metadata{Rows: 100, InvalidCICs: invalid}); r.OK() != ok {
+			t.Errorf("%d invalid: got %s, want OK %t", invalid, r, ok)
+		}
+	}
+}
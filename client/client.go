@@ -0,0 +1,327 @@
+// Package client looks up securities from a lem server over HTTP.  A Client is a
+// fast_lem.Getter, so it can replace a Bolt database or an in-memory SecurityMaster.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nycmonkey/fast_lem"
+	"github.com/pquerna/ffjson/ffjson"
+)
+
+const (
+	DefaultBatchSize   = 1000
+	DefaultConcurrency = 4
+	DefaultMaxRetries  = 3
+	DefaultBackoff     = 100 * time.Millisecond
+)
+
+// StatusError reports a response from the server other than 200 OK
+type StatusError struct {
+	StatusCode int
+	Message    string
+	// RetryAfter is the delay requested by the server's Retry-After header, if any
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("lem server responded %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Temporary reports whether the request may succeed if retried
+func (e *StatusError) Temporary() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Client looks up securities from the lem server at URL.  Its zero value is not
+// usable; create one with New and adjust its fields before first use.
+type Client struct {
+	// URL is the server's base URL, such as http://localhost:8888
+	URL string
+	// APIKey, if set, is sent in the X-API-Key header
+	APIKey string
+	// HTTPClient sends the requests; its connections are reused across lookups
+	HTTPClient *http.Client
+	// BatchSize is the most keys sent in one request; longer key lists are split
+	BatchSize int
+	// Concurrency is the most requests in flight for one lookup
+	Concurrency int
+	// MaxRetries is the number of times a request is retried after a network error
+	// or a temporary StatusError
+	MaxRetries int
+	// Backoff is the delay before the first retry, doubled before each further retry
+	Backoff time.Duration
+	// ExcludeTerminated asks the server to report securities terminated as of today
+	// as fast_lem.Terminated instead of returning them
+	ExcludeTerminated bool
+}
+
+// New returns a Client for the server at url with the default settings
+func New(url string) *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = DefaultConcurrency
+	return &Client{
+		URL:         strings.TrimSuffix(url, "/"),
+		HTTPClient:  &http.Client{Transport: transport},
+		BatchSize:   DefaultBatchSize,
+		Concurrency: DefaultConcurrency,
+		MaxRetries:  DefaultMaxRetries,
+		Backoff:     DefaultBackoff,
+	}
+}
+
+// Get "hydrates" security details from one or more identifiers
+func (c *Client) Get(keys ...string) ([]*fast_lem.Security, error) {
+	return c.GetContext(context.Background(), keys...)
+}
+
+// GetContext "hydrates" security details from one or more identifiers, giving up if
+// ctx is done first
+func (c *Client) GetContext(ctx context.Context, keys ...string) ([]*fast_lem.Security, error) {
+	results, err := c.Lookup(ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+	return fast_lem.Securities(results)
+}
+
+// Lookup resolves each key independently, in batches of at most BatchSize keys
+func (c *Client) Lookup(ctx context.Context, keys ...string) ([]fast_lem.Result, error) {
+	results := make([]fast_lem.Result, len(keys))
+	batchSize := c.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	concurrency := c.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	sem := make(chan struct{}, concurrency)
+	for start := 0; start < len(keys); start += batchSize {
+		end := start + batchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			err := c.lookupBatch(ctx, keys[start:end], results[start:end])
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(start, end)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// lookupBatch resolves keys with one query, retrying temporary failures, and stores
+// their outcomes in results
+func (c *Client) lookupBatch(ctx context.Context, keys []string, results []fast_lem.Result) error {
+	body, err := ffjson.Marshal(&fast_lem.Request{Keys: keys, WithStatus: true, ExcludeTerminated: c.ExcludeTerminated})
+	if err != nil {
+		return err
+	}
+	var response *fast_lem.StatusResponse
+	err = c.retry(ctx, func() error {
+		response = &fast_lem.StatusResponse{}
+		return c.do(ctx, "POST", "/query", body, response)
+	})
+	if err != nil {
+		return err
+	}
+	if len(response.Results) != len(keys) {
+		return fmt.Errorf("lem server returned %d results for %d keys", len(response.Results), len(keys))
+	}
+	for i, kr := range response.Results {
+		results[i] = kr.Result()
+	}
+	return nil
+}
+
+// Search returns the page of Securities matching f
+func (c *Client) Search(ctx context.Context, f *fast_lem.Filter) (*fast_lem.SearchResponse, error) {
+	response := &fast_lem.SearchResponse{}
+	err := c.retry(ctx, func() error {
+		*response = fast_lem.SearchResponse{}
+		return c.do(ctx, "GET", "/search?"+f.Values().Encode(), nil, response)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// ParentChain returns the legal entity with id followed by each of its parents in
+// turn, ending with its ultimate parent
+func (c *Client) ParentChain(ctx context.Context, id string) ([]fast_lem.Entity, error) {
+	response := &fast_lem.HierarchyResponse{}
+	err := c.retry(ctx, func() error {
+		*response = fast_lem.HierarchyResponse{}
+		return c.do(ctx, "GET", "/hierarchy?"+url.Values{"entity": {id}}.Encode(), nil, response)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.Chain, nil
+}
+
+// Describe returns the load metadata of the data the server holds
+func (c *Client) Describe() (*fast_lem.Metadata, error) {
+	info, err := c.Info(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	if info.Metadata == nil {
+		return nil, fast_lem.ErrNoMetadata
+	}
+	return info.Metadata, nil
+}
+
+// Info returns the server's description of itself and the data it holds
+func (c *Client) Info(ctx context.Context) (*fast_lem.Info, error) {
+	info := &fast_lem.Info{}
+	err := c.retry(ctx, func() error {
+		return c.do(ctx, "GET", "/info", nil, info)
+	})
+	return info, err
+}
+
+// retry calls fn until it succeeds, fails permanently or has been retried MaxRetries
+// times, backing off between attempts
+func (c *Client) retry(ctx context.Context, fn func() error) error {
+	backoff := c.Backoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= c.MaxRetries || !temporary(ctx, err) {
+			return err
+		}
+		wait := backoff + time.Duration(rand.Int63n(int64(backoff)/2+1))
+		var se *StatusError
+		if errors.As(err, &se) && se.RetryAfter > wait {
+			wait = se.RetryAfter
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// temporary reports whether err may not recur
+func temporary(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Temporary()
+	}
+	// anything else is a failure to reach the server or to read its response
+	var de *decodeError
+	return !errors.As(err, &de)
+}
+
+// decodeError reports a response that could not be decoded
+type decodeError struct {
+	err error
+}
+
+func (e *decodeError) Error() string { return "decode lem server response: " + e.err.Error() }
+
+// do sends a request and decodes a successful JSON response into v.  Securities are
+// requested in fast_lem.StructuredFormat, which keeps coupons at full precision and
+// issue type codes this process has not registered.
+func (c *Client) do(ctx context.Context, method, path string, body []byte, v interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.URL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", fast_lem.StructuredMediaType)
+	if len(c.APIKey) > 0 {
+		req.Header.Set("X-API-Key", c.APIKey)
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// drain the body so the connection can be reused
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		se := &StatusError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			se.RetryAfter = time.Duration(seconds) * time.Second
+		}
+		return se
+	}
+	if u, ok := v.(json.Unmarshaler); ok {
+		err = u.UnmarshalJSON(data)
+	} else {
+		err = json.Unmarshal(data, v)
+	}
+	if err != nil {
+		return &decodeError{err}
+	}
+	return nil
+}
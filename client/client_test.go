@@ -0,0 +1,132 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nycmonkey/fast_lem"
+)
+
+func testServer(t *testing.T, wrap func(http.HandlerFunc) http.HandlerFunc) (*httptest.Server, *Client) {
+	c := make(chan *fast_lem.Security, 10)
+	c <- fast_lem.New("FDS010000", "USFDS0100006", "B0YBKJ7", "", "000XT9-E", "LN", "", "2010-07-21")
+	c <- fast_lem.New("851500000", "US8515000006", "", "", "8C7QCS-E", "MU", "5", "2007-07-01")
+	c <- fast_lem.New("FDS020000", "", "B0YBKL9", "XYZ", "000XT9-E", "EQ", "", "")
+	c <- fast_lem.New("FDS030000", "", "", "", "", "BD", "5.125", "2030-01-15")
+	c <- fast_lem.New("FDS040000", "", "", "", "", "QZ", "", "")
+	close(c)
+	m, err := fast_lem.NewSecurityMasterFromUnsorted(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := fast_lem.Server{Getter: m}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/query", wrap(server.QueryHandler))
+	mux.HandleFunc("/info", server.InfoHandler)
+	ts := httptest.NewServer(mux)
+	client := New(ts.URL)
+	client.Backoff = time.Millisecond
+	return ts, client
+}
+
+func noWrap(h http.HandlerFunc) http.HandlerFunc { return h }
+
+func TestGet(t *testing.T) {
+	var requests int32
+	ts, client := testServer(t, func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			h(w, r)
+		}
+	})
+	defer ts.Close()
+	client.BatchSize = 2
+	keys := []string{"851500000", "USFDS0100006", "B0YBKL9", "NOPE00000", "FDS020000"}
+	response, err := client.Get(keys...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"851500000", "FDS010000", "FDS020000", "", "FDS020000"}
+	for i, s := range response {
+		if s.CUSIP != want[i] {
+			t.Errorf("%s: got CUSIP '%s', want '%s'", keys[i], s.CUSIP, want[i])
+		}
+	}
+	if response[0].Description.Coupon != 5 || response[2].Ticker != "XYZ" {
+		t.Errorf("Securities not fully decoded: %+v, %+v", response[0], response[2])
+	}
+	exact, err := client.Get("FDS030000", "FDS040000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exact[0].Description.Coupon != 5.125 || exact[1].Description.Code() != "QZ" {
+		t.Errorf("Got coupon %g and issue type %q, want 5.125 and QZ", exact[0].Description.Coupon, exact[1].Description.Code())
+	}
+	if requests != 4 {
+		t.Errorf("Got %d requests for %d keys in batches of 2 and one more batch, want 4", requests, len(keys))
+	}
+	results, err := client.Lookup(context.Background(), "851500000", "", "NOPE00000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []fast_lem.Status{fast_lem.Found, fast_lem.Invalid, fast_lem.NotFound} {
+		if results[i].Status != want {
+			t.Errorf("%q: got status %s, want %s", results[i].Key, results[i].Status, want)
+		}
+	}
+}
+
+func TestRetries(t *testing.T) {
+	var failures int32 = 2
+	ts, client := testServer(t, func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&failures, -1) >= 0 {
+				http.Error(w, "loading", http.StatusServiceUnavailable)
+				return
+			}
+			h(w, r)
+		}
+	})
+	defer ts.Close()
+	response, err := client.Get("851500000")
+	if err != nil || response[0].CUSIP != "851500000" {
+		t.Errorf("Got %v, %v after temporary failures", response, err)
+	}
+	client.MaxRetries = 0
+	failures = 1
+	if _, err = client.Get("851500000"); err == nil {
+		t.Error("Got no error without retries")
+	}
+}
+
+func TestPermanentFailure(t *testing.T) {
+	var requests int32
+	ts, client := testServer(t, func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			http.Error(w, "no", http.StatusForbidden)
+		}
+	})
+	defer ts.Close()
+	_, err := client.Get("851500000")
+	if se, ok := err.(*StatusError); !ok || se.StatusCode != http.StatusForbidden {
+		t.Errorf("Got error %v, want a 403 StatusError", err)
+	}
+	if requests != 1 {
+		t.Errorf("Got %d requests, want no retries", requests)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	ts, client := testServer(t, noWrap)
+	defer ts.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.GetContext(ctx, "851500000"); err != context.Canceled {
+		t.Errorf("Got error %v, want %v", err, context.Canceled)
+	}
+}
@@ -1,22 +1,232 @@
 package main
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
 	"flag"
+	"fmt"
+	"hash"
 	"log"
 	"os"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/boltdb/bolt"
 )
 
 var (
-	bigdb string
+	bigdb     string
+	output    string
+	inPlace   bool
+	backup    string
+	fill      float64
+	batchSize int
+	dump      bool
 )
 
 func init() {
-	flag.StringVar(&bigdb, "bigdb", "../db/lem.db", "path to the database to be dumped")
+	flag.StringVar(&bigdb, "bigdb", "../db/lem.db", "path to the database to be compacted")
+	flag.StringVar(&output, "output", "",
+		"path for the compacted copy; defaults to the source path plus .compact")
+	flag.BoolVar(&inPlace, "inplace", false,
+		"replace the source with the verified compacted copy, keeping a backup")
+	flag.StringVar(&backup, "backup", "",
+		"path for the backup kept by -inplace; defaults to the source path plus .bak")
+	flag.Float64Var(&fill, "fill", 1.0,
+		"bucket fill percent for the copy; 1.0 packs pages fully since keys arrive in order")
+	flag.IntVar(&batchSize, "batch", 50000, "number of keys written per transaction")
+	flag.BoolVar(&dump, "dump", false,
+		"write a raw copy of the database, free pages included, to stdout and exit")
 	flag.Parse()
 }
+
+// digest summarises the contents of one bucket
+type digest struct {
+	Keys int
+	Sum  hash.Hash
+}
+
+// compactor copies buckets into dst, committing every batchSize keys
+type compactor struct {
+	dst     *bolt.DB
+	tx      *bolt.Tx
+	pending int
+	buckets map[string]*bolt.Bucket
+}
+
+func (c *compactor) begin() (err error) {
+	c.tx, err = c.dst.Begin(true)
+	c.pending = 0
+	c.buckets = make(map[string]*bolt.Bucket)
+	return
+}
+
+func (c *compactor) commit() error {
+	return c.tx.Commit()
+}
+
+// bucket returns the destination bucket at path in the current transaction,
+// creating it if necessary
+func (c *compactor) bucket(path [][]byte) (b *bolt.Bucket, err error) {
+	name := string(bytes.Join(path, []byte{0}))
+	if b = c.buckets[name]; b != nil {
+		return
+	}
+	if len(path) == 1 {
+		b, err = c.tx.CreateBucketIfNotExists(path[0])
+	} else {
+		var parent *bolt.Bucket
+		parent, err = c.bucket(path[:len(path)-1])
+		if err != nil {
+			return
+		}
+		b, err = parent.CreateBucketIfNotExists(path[len(path)-1])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create bucket %q: %s", name, err)
+	}
+	b.FillPercent = fill
+	c.buckets[name] = b
+	return
+}
+
+func (c *compactor) copyBucket(src *bolt.Bucket, path [][]byte) error {
+	_, err := c.bucket(path)
+	if err != nil {
+		return err
+	}
+	return src.ForEach(func(k, v []byte) error {
+		if v == nil {
+			return c.copyBucket(src.Bucket(k), append(path[:len(path):len(path)], k))
+		}
+		if c.pending >= batchSize {
+			if err := c.commit(); err != nil {
+				return err
+			}
+			if err := c.begin(); err != nil {
+				return err
+			}
+		}
+		b, err := c.bucket(path)
+		if err != nil {
+			return err
+		}
+		c.pending++
+		return b.Put(k, v)
+	})
+}
+
+// compact re-inserts every key of src into a fresh database at path
+func compact(src *bolt.DB, path string) error {
+	dst, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+	c := &compactor{dst: dst}
+	if err = c.begin(); err != nil {
+		return err
+	}
+	err = src.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			return c.copyBucket(b, [][]byte{name})
+		})
+	})
+	if err != nil {
+		c.tx.Rollback()
+		return err
+	}
+	if err = c.commit(); err != nil {
+		return err
+	}
+	return dst.Close()
+}
+
+// digests returns a digest of every bucket in db, keyed by slash-separated path
+func digests(db *bolt.DB) (d map[string]*digest, err error) {
+	d = make(map[string]*digest)
+	var walk func(b *bolt.Bucket, path string) error
+	walk = func(b *bolt.Bucket, path string) error {
+		bd := &digest{Sum: sha256.New()}
+		d[path] = bd
+		var n [binary.MaxVarintLen64]byte
+		return b.ForEach(func(k, v []byte) error {
+			if v == nil {
+				return walk(b.Bucket(k), path+"/"+string(k))
+			}
+			bd.Keys++
+			bd.Sum.Write(n[:binary.PutUvarint(n[:], uint64(len(k)))])
+			bd.Sum.Write(k)
+			bd.Sum.Write(n[:binary.PutUvarint(n[:], uint64(len(v)))])
+			bd.Sum.Write(v)
+			return nil
+		})
+	}
+	err = db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			return walk(b, string(name))
+		})
+	})
+	return
+}
+
+// verify compares the contents of the source with the compacted copy at path,
+// printing the key count and checksum of each bucket
+func verify(src *bolt.DB, path string) error {
+	dst, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+	want, err := digests(src)
+	if err != nil {
+		return err
+	}
+	got, err := digests(dst)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(want))
+	for name := range want {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var problems []string
+	for _, name := range names {
+		w, g := want[name], got[name]
+		delete(got, name)
+		if g == nil {
+			problems = append(problems, name+": missing from copy")
+			continue
+		}
+		ws, gs := w.Sum.Sum(nil), g.Sum.Sum(nil)
+		fmt.Printf("%-20s %10d keys  sha256 %x\n", name, g.Keys, gs)
+		if w.Keys != g.Keys {
+			problems = append(problems, fmt.Sprintf("%s: %d keys in copy, want %d", name, g.Keys, w.Keys))
+		} else if !bytes.Equal(ws, gs) {
+			problems = append(problems, fmt.Sprintf("%s: checksum %x, want %x", name, gs, ws))
+		}
+	}
+	for name := range got {
+		problems = append(problems, name+": not in source")
+	}
+	if len(problems) > 0 {
+		return errors.New("verification failed:\n" + strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+func fileSize(path string) int64 {
+	fi, err := os.Stat(path)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return fi.Size()
+}
+
 func main() {
 	var db *bolt.DB
 	var err error
@@ -25,14 +235,53 @@ func main() {
 		log.Fatalln("Error opening db:", err)
 	}
 	defer db.Close()
+	if dump {
+		err = db.View(func(tx *bolt.Tx) error {
+			_, err = tx.WriteTo(os.Stdout)
+			return err
+		})
+		if err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+	if len(output) == 0 {
+		output = bigdb + ".compact"
+	}
+	if len(backup) == 0 {
+		backup = bigdb + ".bak"
+	}
+	if _, err = os.Stat(output); err == nil {
+		log.Fatalln(output, "already exists")
+	}
+	start := time.Now()
+	err = compact(db, output)
+	if err != nil {
+		os.Remove(output)
+		log.Fatalln("Compaction failed:", err)
+	}
+	fmt.Println("Compacted", bigdb, "to", output, "in", time.Now().Sub(start))
+	err = verify(db, output)
 	if err != nil {
 		log.Fatalln(err)
 	}
-	err = db.View(func(tx *bolt.Tx) error {
-		_, err = tx.WriteTo(os.Stdout)
-		return err
-	})
+	before, after := fileSize(bigdb), fileSize(output)
+	fmt.Printf("Size: %d -> %d bytes, saved %d bytes (%.1f%%)\n",
+		before, after, before-after, 100*float64(before-after)/float64(before))
+	if !inPlace {
+		return
+	}
+	db.Close()
+	if _, err = os.Stat(backup); err == nil {
+		log.Fatalln(backup, "already exists; leaving", output, "in place")
+	}
+	err = os.Rename(bigdb, backup)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = os.Rename(output, bigdb)
 	if err != nil {
 		log.Fatalln(err)
 	}
+	fmt.Printf("Replaced %s; original kept at %s\n", bigdb, backup)
 }
@@ -0,0 +1,68 @@
+package fast_lem
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid page token")
+)
+
+// processCursorKey seals the page tokens of a Server without a CursorKey, so that
+// its tokens are only good until it restarts
+var processCursorKey = func() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}()
+
+func (s Server) cursorAEAD() (cipher.AEAD, error) {
+	key := s.CursorKey
+	if len(key) == 0 {
+		key = processCursorKey
+	}
+	sum := sha256.Sum256(key)
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealCursor returns an opaque page token standing for the CUSIP a page ends with
+func (s Server) sealCursor(cusip string) (string, error) {
+	aead, err := s.cursorAEAD()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(cusip), nil)), nil
+}
+
+// openCursor returns the CUSIP sealed in token by sealCursor
+func (s Server) openCursor(token string) (string, error) {
+	aead, err := s.cursorAEAD()
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", ErrInvalidCursor
+	}
+	n := aead.NonceSize()
+	cusip, err := aead.Open(nil, sealed[:n], sealed[n:], nil)
+	if err != nil {
+		return "", ErrInvalidCursor
+	}
+	return string(cusip), nil
+}
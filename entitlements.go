@@ -0,0 +1,169 @@
+package fast_lem
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pquerna/ffjson/ffjson"
+)
+
+// EntitledFields are the members of a Security's JSON representation that an
+// Entitlement can allow.  Members not listed here are never shown to clients whose
+// Fields are restricted.
+var EntitledFields = []string{"LegalEntityId", "Cusip", "ISIN", "Sedol", "Ticker", "Country", "Currency",
+	"CIC", "Inception", "Termination", "Description", "Status"}
+
+// Entitlement limits what a client is licensed to see
+type Entitlement struct {
+	// Fields are the EntitledFields the client may see; all of them if empty
+	Fields []string `json:",omitempty"`
+	// IdentifierTypes are the types of key (CUSIP, ISIN or SEDOL) the client may look
+	// up; all of them if empty
+	IdentifierTypes []string `json:",omitempty"`
+}
+
+func (e *Entitlement) validate() error {
+	for _, f := range e.Fields {
+		if !contains(EntitledFields, f) {
+			return fmt.Errorf("unknown field %q; expected one of %v", f, EntitledFields)
+		}
+	}
+	for _, t := range e.IdentifierTypes {
+		switch t {
+		case CUSIPIdentifier.String(), ISINIdentifier.String(), SEDOLIdentifier.String():
+		default:
+			return fmt.Errorf("unknown identifier type %q; expected CUSIP, ISIN or SEDOL", t)
+		}
+	}
+	return nil
+}
+
+// RestrictsFields reports whether e hides any Security fields
+func (e *Entitlement) RestrictsFields() bool {
+	return e != nil && len(e.Fields) > 0
+}
+
+// AllowsType reports whether a client entitled to e may look up keys of type t
+func (e *Entitlement) AllowsType(t IdentifierType) bool {
+	if e == nil || len(e.IdentifierTypes) == 0 || t == InvalidIdentifier {
+		return true
+	}
+	return contains(e.IdentifierTypes, t.String())
+}
+
+// DeniedFields returns the EntitledFields hidden by e
+func (e *Entitlement) DeniedFields() (denied []string) {
+	if !e.RestrictsFields() {
+		return nil
+	}
+	for _, f := range EntitledFields {
+		if !contains(e.Fields, f) {
+			denied = append(denied, f)
+		}
+	}
+	return
+}
+
+// Redact returns s as a value that marshals to JSON holding only the fields e allows
+func (e *Entitlement) Redact(s *Security) json.Marshaler {
+	return entitledSecurity{s: s, fields: e.Fields}
+}
+
+// EntitlementFrom returns the entitlement of the client making the request ctx
+// belongs to, or nil if the request is unrestricted
+func EntitlementFrom(ctx context.Context) *Entitlement {
+	if c := ClientFrom(ctx); c != nil {
+		return &c.Entitlement
+	}
+	return nil
+}
+
+// entitledSecurity marshals the allowed members of a Security, in their usual order
+type entitledSecurity struct {
+	s      *Security
+	fields []string
+}
+
+func (es entitledSecurity) MarshalJSON() ([]byte, error) {
+	full, err := ffjson.Marshal(es.s)
+	if err != nil {
+		return nil, err
+	}
+	var members map[string]json.RawMessage
+	err = json.Unmarshal(full, &members)
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	buf.WriteByte('{')
+	for _, f := range EntitledFields {
+		v, ok := members[f]
+		if !ok || !contains(es.fields, f) {
+			continue
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(buf, "%q:", f)
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// entitledKeyResult is a KeyResult whose Security has been redacted
+type entitledKeyResult struct {
+	Key      string
+	Type     string `json:",omitempty"`
+	Status   string
+	Error    string         `json:",omitempty"`
+	Security json.Marshaler `json:",omitempty"`
+}
+
+// entitledStatusResponse is a StatusResponse whose Securities have been redacted
+type entitledStatusResponse struct {
+	Results      []*entitledKeyResult
+	DeniedFields []string `json:",omitempty"`
+}
+
+// lookupEntitled resolves keys with g, except for keys of types e does not allow,
+// which are not looked up and are reported as Denied
+func lookupEntitled(ctx context.Context, g Getter, e *Entitlement, keys []string) ([]Result, error) {
+	if e == nil || len(e.IdentifierTypes) == 0 {
+		return Lookup(ctx, g, keys...)
+	}
+	results := make([]Result, len(keys))
+	var allowed []string
+	var allowedAt []int
+	for i, k := range keys {
+		t := IdentifierTypeOf(k)
+		if !e.AllowsType(t) {
+			results[i] = Result{Key: k, Type: t, Status: Denied}
+			continue
+		}
+		allowed = append(allowed, k)
+		allowedAt = append(allowedAt, i)
+	}
+	if len(allowed) == 0 {
+		return results, nil
+	}
+	resolved, err := Lookup(ctx, g, allowed...)
+	if err != nil {
+		return nil, err
+	}
+	for j, i := range allowedAt {
+		results[i] = resolved[j]
+	}
+	return results, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package fast_lem
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEntitlements(t *testing.T) {
+	registry, err := NewClientRegistry(&Client{
+		Name:        "xref",
+		Keys:        []string{"secret"},
+		Entitlement: Entitlement{Fields: []string{"Cusip", "ISIN"}, IdentifierTypes: []string{"CUSIP"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := testSecurities()
+	server := Server{Getter: mapGetter{s[0].CUSIP: s[0], s[1].CUSIP: s[1], s[1].SEDOL: s[1]}}
+	query := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/query", strings.NewReader(body))
+		r.Header.Set("X-API-Key", "secret")
+		w := httptest.NewRecorder()
+		Guard(registry, nil, server.QueryHandler)(w, r)
+		return w
+	}
+	w := query(`{"Keys":["851500000","B0YBKJ7"]}`)
+	want := `[{"Cusip":"851500000","ISIN":"` + s[0].ISIN + `"},{}]`
+	if w.Body.String() != want {
+		t.Errorf("Got %s, want %s", w.Body, want)
+	}
+	if w.Header().Get("X-Denied-Fields") != "LegalEntityId,Sedol,Ticker,Country,Currency,CIC,Inception,Termination,Description,Status" || w.Header().Get("X-Denied-Keys") != "1" {
+		t.Errorf("Unexpected headers %v", w.Header())
+	}
+	w = query(`{"Keys":["FDS010000","B0YBKJ7"],"WithStatus":true}`)
+	want = `{"Results":[{"Key":"FDS010000","Type":"CUSIP","Status":"found","Security":{"Cusip":"FDS010000","ISIN":"` + s[1].ISIN + `"}},` +
+		`{"Key":"B0YBKJ7","Type":"SEDOL","Status":"denied"}],` +
+		`"DeniedFields":["LegalEntityId","Sedol","Ticker","Country","Currency","CIC","Inception","Termination","Description","Status"]}`
+	if w.Body.String() != want {
+		t.Errorf("Got %s, want %s", w.Body, want)
+	}
+	if _, err = NewClientRegistry(&Client{Name: "bad", Entitlement: Entitlement{Fields: []string{"Price"}}}); err == nil {
+		t.Error("Accepted an entitlement to an unknown field")
+	}
+}
@@ -1,12 +1,15 @@
 package main
 
 import (
-	"errors"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
@@ -20,12 +23,23 @@ var (
 	wg          = new(sync.WaitGroup)
 	storage     fast_lem.Storage
 	recordCount int
+	rejectCount int
+	invalidCICs int
+	typeCounts  = make(map[string]int)
+	sourceFile  fast_lem.SourceFile
+	checkFile   string
+	entityFile  string
 )
 
 func init() {
 	flag.StringVar(&source, "source", "data.csv", "path to the source data")
 	flag.StringVar(&dbfile, "output", "../db/lem.db",
 		"path to a boltdb database where the data will be stored")
+	flag.StringVar(&checkFile, "checks", "",
+		"path to a JSON data-quality check suite, such as etl/checks.example.json; the built-in "+
+			"suite, which only requires some rows, is used if empty")
+	flag.StringVar(&entityFile, "entities", "",
+		"path to a FactSet entity structure file giving each entity's parent; none is loaded if empty")
 	flag.Parse()
 }
 
@@ -43,26 +57,29 @@ const (
 	_ // FS_PERM_SEC_ID
 	colEntityID
 	_ // SECURITY_NAME
-	_ // ISO_COUNTRY
+	colCountry
 	colIssueType
 	_ // FDS_PRIMARY_MIC_EXCHANGE_CODE
-	_ // INCEPTION_DATE
-	_ // TERMINATION_DATE
+	colInceptionDate
+	colTerminationDate
 	_ // CAP_GROUP
-	_ // FDS_PRIMARY_ISO_CURRENCY
-	_ // CIC_CODE
+	colCurrency
+	colCIC
 	colCouponRate
 	colMaturityDate
 )
 
-// ReadData reads Security data from source and pushes batches
+// ReadData reads Security data from source and pushes batches.  Rows that cannot
+// be parsed are logged and counted as rejects.
 func ReadData(c chan *fast_lem.Security) {
 	data, err := os.Open(source)
 	if err != nil {
 		log.Fatalln(err)
 	}
 	defer data.Close()
-	r := fast_lem.NewReader(data)
+	h := sha256.New()
+	counted := &countingReader{r: io.TeeReader(data, h)}
+	r := fast_lem.NewReader(counted)
 	r.FieldsPerRecord = 17
 	var row []string
 	row, err = r.Read()
@@ -75,10 +92,15 @@ func ReadData(c chan *fast_lem.Security) {
 			if err == io.EOF {
 				break
 			}
+			if _, ok := err.(*csv.ParseError); ok {
+				log.Println("Rejected:", err)
+				rejectCount++
+				continue
+			}
 			log.Fatalln(err)
 		}
-		recordCount++
-		security := fast_lem.New(row[colCUSIP],
+		var security *fast_lem.Security
+		security, err = fast_lem.NewSecurity(row[colCUSIP],
 			row[colISIN],
 			row[colSEDOL],
 			row[colTicker],
@@ -86,12 +108,72 @@ func ReadData(c chan *fast_lem.Security) {
 			row[colIssueType],
 			row[colCouponRate],
 			row[colMaturityDate])
+		if err != nil {
+			log.Println("Rejected", row[colCUSIP]+":", err)
+			rejectCount++
+			continue
+		}
+		err = security.SetLifecycleDates(row[colInceptionDate], row[colTerminationDate])
+		if err != nil {
+			log.Println("Rejected", row[colCUSIP]+":", err)
+			rejectCount++
+			continue
+		}
+		security.Country = row[colCountry]
+		security.Currency = row[colCurrency]
+		security.CIC = fast_lem.CIC(row[colCIC])
+		if err = security.ValidateCIC(); err != nil {
+			// keep the security and its code; the checks decide whether too many are bad
+			log.Println("Invalid CIC for", row[colCUSIP]+":", err)
+			invalidCICs++
+		}
+		recordCount++
+		typeCounts[row[colIssueType]]++
 		c <- security
 	}
+	sourceFile = fast_lem.SourceFile{
+		Name:   filepath.Base(source),
+		Size:   counted.n,
+		SHA256: hex.EncodeToString(h.Sum(nil)),
+	}
 	close(c)
 	return
 }
 
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (n int, err error) {
+	n, err = cr.r.Read(p)
+	cr.n += int64(n)
+	return
+}
+
+// LoadEntities stores the corporate structure in entityFile in db, and returns the
+// number of entities and a description of the file
+func LoadEntities(db *bolt.DB) (int, fast_lem.SourceFile, error) {
+	f, err := os.Open(entityFile)
+	if err != nil {
+		return 0, fast_lem.SourceFile{}, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	counted := &countingReader{r: io.TeeReader(f, h)}
+	entities, err := fast_lem.ReadEntityStructure(counted)
+	if err != nil {
+		return 0, fast_lem.SourceFile{}, fmt.Errorf("%s: %s", entityFile, err)
+	}
+	err = fast_lem.WriteEntities(db, entities)
+	return len(entities), fast_lem.SourceFile{
+		Name:   filepath.Base(entityFile),
+		Size:   counted.n,
+		SHA256: hex.EncodeToString(h.Sum(nil)),
+	}, err
+}
+
 // PersistData stores Securities in batches
 func PersistData(c chan *fast_lem.Security) {
 	storage.Store(c)
@@ -99,33 +181,50 @@ func PersistData(c chan *fast_lem.Security) {
 	return
 }
 
-func checkKnownValue() (string, error) {
-	var ISIN = `US00037NMH60`
-	var EntityID = `06L3Q8-E`
-	response, err := storage.Get(ISIN)
-	if err != nil {
-		return "", err
+// previousRows returns the row count recorded by the load currently published at
+// dbfile, or zero if there is none
+func previousRows() int {
+	if _, err := os.Stat(dbfile); err != nil {
+		return 0
 	}
-	if len(response) < 1 {
-		return "", errors.New("Response was empty")
+	db, err := bolt.Open(dbfile, 0600, &bolt.Options{Timeout: 1 * time.Second, ReadOnly: true})
+	if err != nil {
+		log.Fatalln("Error opening previous db:", err)
 	}
-	security := response[0]
-	if security.LegalEntityID != EntityID {
-		return "", errors.New("Unexpected entity ID: " + security.LegalEntityID)
+	defer db.Close()
+	prev, err := fast_lem.ReadMetadata(db)
+	if err != nil {
+		log.Println("Previous load:", err)
+		return 0
 	}
-	return `Test OK: ` + ISIN + ` => ` + fmt.Sprintf("%+v", security), nil
+	return prev.Rows
 }
 
+// main loads the source into a new database alongside dbfile, and replaces dbfile
+// with it only if the data-quality checks pass
 func main() {
-	start := time.Now()
+	checks := fast_lem.DefaultChecks
+	var err error
+	if len(checkFile) > 0 {
+		checks, err = fast_lem.LoadCheckSuite(checkFile)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+	meta := fast_lem.NewMetadata()
+	start := meta.Started
+	meta.PreviousRows = previousRows()
 	c := make(chan *fast_lem.Security, 20000)
+	staging := dbfile + ".tmp"
+	err = os.Remove(staging)
+	if err != nil && !os.IsNotExist(err) {
+		log.Fatalln(err)
+	}
 	var db *bolt.DB
-	var err error
-	db, err = bolt.Open(dbfile, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	db, err = bolt.Open(staging, 0600, &bolt.Options{Timeout: 1 * time.Second})
 	if err != nil {
 		log.Fatalln(err)
 	}
-	defer db.Close()
 	storage, err = fast_lem.NewStorage(db)
 	if err != nil {
 		log.Fatalln(err)
@@ -134,12 +233,41 @@ func main() {
 	wg.Add(1)
 	go PersistData(c)
 	wg.Wait()
-	fmt.Println("ETL completed in", time.Now().Sub(start).Minutes(), "minutes")
+	meta.Finished = time.Now()
+	meta.Sources = []fast_lem.SourceFile{sourceFile}
+	meta.Rows = recordCount
+	meta.Rejects = rejectCount
+	meta.InvalidCICs = invalidCICs
+	meta.Counts = typeCounts
+	if len(entityFile) > 0 {
+		var entitySource fast_lem.SourceFile
+		meta.Entities, entitySource, err = LoadEntities(db)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		meta.Sources = append(meta.Sources, entitySource)
+		fmt.Println("Loaded", meta.Entities, "entities")
+	}
+	err = fast_lem.WriteMetadata(db, meta)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	fmt.Println("ETL completed in", meta.Finished.Sub(start).Minutes(), "minutes")
 	fmt.Println("Loaded", recordCount, "records")
-	sanityCheck, err := checkKnownValue()
+	fmt.Println("Rejected", rejectCount, "records")
+	report := checks.Run(storage, meta)
+	fmt.Print(report)
+	err = db.Close()
 	if err != nil {
-		log.Println(err)
+		log.Fatalln(err)
+	}
+	if !report.OK() {
+		log.Fatalln("Checks failed; leaving", staging, "unpublished")
+	}
+	err = os.Rename(staging, dbfile)
+	if err != nil {
+		log.Fatalln(err)
 	}
-	fmt.Println(sanityCheck)
+	fmt.Println("Published", dbfile)
 	return
 }
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/nycmonkey/fast_lem"
+	"github.com/pquerna/ffjson/ffjson"
+)
+
+var (
+	dbfile     string
+	output     string
+	format     string
+	fields     []string
+	issueTypes map[string]bool
+	countries  map[string]bool
+	activeOnly bool
+	asOf       time.Time
+
+	fieldList, issueTypeList, issueTypeConfig, countryList, asOfDate string
+)
+
+func init() {
+	flag.StringVar(&dbfile, "dbfile", "../db/lem.db", "path to the boltdb database to export")
+	flag.StringVar(&output, "output", "", "path of the file to write; stdout if empty")
+	flag.StringVar(&format, "format", "ndjson", "output format: ndjson, csv, psv or parquet")
+	flag.StringVar(&fieldList, "fields", strings.Join(fast_lem.SecurityFields, ","),
+		"comma-separated fields to export")
+	flag.StringVar(&issueTypeList, "issuetype", "", "comma-separated issue type codes to export; all if empty")
+	flag.StringVar(&issueTypeConfig, "issuetypes", "",
+		"path to a JSON array of issue types FactSet added since this tool was built")
+	flag.StringVar(&countryList, "country", "", "comma-separated ISO country codes to export; all if empty")
+	flag.BoolVar(&activeOnly, "active", false, "export only securities that have not terminated or matured")
+	flag.StringVar(&asOfDate, "asof", "", "date (YYYY-MM-DD) used by -active and for the Status field; today if empty")
+}
+
+// parseFlags parses the command line.  It is called from main rather than init so
+// the test binary can register its own flags first.
+func parseFlags() {
+	flag.Parse()
+	if len(issueTypeConfig) > 0 {
+		if err := fast_lem.LoadIssueTypes(issueTypeConfig); err != nil {
+			log.Fatalln("Error loading issue types:", err)
+		}
+	}
+	fields = strings.Split(fieldList, ",")
+	issueTypes = set(issueTypeList)
+	countries = set(countryList)
+	asOf = time.Now()
+	if len(asOfDate) > 0 {
+		var err error
+		asOf, err = time.Parse(fast_lem.FactSetDateFormat, asOfDate)
+		if err != nil {
+			log.Fatalln("Invalid -asof:", err)
+		}
+	}
+}
+
+func set(list string) map[string]bool {
+	if len(list) == 0 {
+		return nil
+	}
+	s := make(map[string]bool)
+	for _, v := range strings.Split(list, ",") {
+		s[strings.TrimSpace(v)] = true
+	}
+	return s
+}
+
+// selected reports whether s passes the command line filters
+func selected(s *fast_lem.Security) bool {
+	if issueTypes != nil && !issueTypes[s.Description.Code()] {
+		return false
+	}
+	if countries != nil && !countries[s.Country] {
+		return false
+	}
+	if activeOnly && s.Lifecycle(asOf) == fast_lem.LifecycleTerminated {
+		return false
+	}
+	return true
+}
+
+// rowWriter writes the selected fields of one Security
+type rowWriter interface {
+	Write(s *fast_lem.Security) error
+	Close() error
+}
+
+type ndjsonWriter struct {
+	w *bufio.Writer
+}
+
+func (nw ndjsonWriter) Write(s *fast_lem.Security) error {
+	record := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		v, _ := s.Field(f)
+		if len(v) == 0 {
+			continue
+		}
+		if f == "Coupon" {
+			record[f] = s.Description.Coupon
+			continue
+		}
+		record[f] = v
+	}
+	js, err := ffjson.Marshal(record)
+	if err != nil {
+		return err
+	}
+	nw.w.Write(js)
+	return nw.w.WriteByte('\n')
+}
+
+func (nw ndjsonWriter) Close() error {
+	return nw.w.Flush()
+}
+
+type csvWriter struct {
+	w   *csv.Writer
+	row []string
+}
+
+func (cw *csvWriter) Write(s *fast_lem.Security) error {
+	for i, f := range fields {
+		cw.row[i], _ = s.Field(f)
+	}
+	return cw.w.Write(cw.row)
+}
+
+func (cw *csvWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+type parquetRowWriter struct {
+	pw  *parquetWriter
+	w   *bufio.Writer
+	row []interface{}
+}
+
+func (prw *parquetRowWriter) Write(s *fast_lem.Security) error {
+	for i, f := range fields {
+		v, _ := s.Field(f)
+		switch {
+		case len(v) == 0:
+			prw.row[i] = nil
+		case f == "Coupon":
+			prw.row[i] = s.Description.Coupon
+		default:
+			prw.row[i] = v
+		}
+	}
+	return prw.pw.Write(prw.row)
+}
+
+func (prw *parquetRowWriter) Close() error {
+	if err := prw.pw.Close(); err != nil {
+		return err
+	}
+	return prw.w.Flush()
+}
+
+func newRowWriter(w io.Writer) (rowWriter, error) {
+	buffered := bufio.NewWriterSize(w, 1<<20)
+	switch format {
+	case "ndjson":
+		return ndjsonWriter{w: buffered}, nil
+	case "csv", "psv":
+		cw := &csvWriter{w: csv.NewWriter(buffered), row: make([]string, len(fields))}
+		if format == "psv" {
+			cw.w.Comma = '|'
+		}
+		return cw, cw.w.Write(fields)
+	case "parquet":
+		pw, err := newParquetWriter(buffered, fields, map[string]bool{"Coupon": true})
+		return &parquetRowWriter{pw: pw, w: buffered, row: make([]interface{}, len(fields))}, err
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+func main() {
+	parseFlags()
+	var s fast_lem.Security
+	for _, f := range fields {
+		if _, ok := s.Field(f); !ok {
+			log.Fatalln("Unknown field", strconv.Quote(f), "- choose from", strings.Join(fast_lem.SecurityFields, ","))
+		}
+	}
+	var db *bolt.DB
+	var err error
+	db, err = bolt.Open(dbfile, 0666, &bolt.Options{Timeout: 1 * time.Second, ReadOnly: true})
+	if err != nil {
+		log.Fatalln("Error opening db:", err)
+	}
+	defer db.Close()
+	out := os.Stdout
+	if len(output) > 0 {
+		out, err = os.Create(output)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer out.Close()
+	}
+	var w rowWriter
+	w, err = newRowWriter(out)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	var exported int
+	err = fast_lem.ForEachSecurity(db, func(s *fast_lem.Security) error {
+		if !selected(s) {
+			return nil
+		}
+		exported++
+		return w.Write(s.AsOf(asOf))
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = w.Close()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	log.Println("Exported", exported, "securities")
+}
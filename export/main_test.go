@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nycmonkey/fast_lem"
+)
+
+func testSecurities() []*fast_lem.Security {
+	bond := fast_lem.New("459200AS0", "US459200AS05", "", "IBM A", "000XT9-E", "BD", "5.125", "2031-11-30")
+	bond.Country, bond.Currency, bond.Inception = "US", "USD", "2001-11-30"
+	equity := fast_lem.New("FDS020000", "", "B0YBKL9", `XYZ "A", Inc|B`, "000XT9-E", "EQ", "", "")
+	return []*fast_lem.Security{bond, equity}
+}
+
+// exportAll writes securities in the given format with every field selected
+func exportAll(t *testing.T, f string, securities []*fast_lem.Security) []byte {
+	format, fields = f, fast_lem.SecurityFields
+	buf := new(bytes.Buffer)
+	w, err := newRowWriter(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range securities {
+		if err = w.Write(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestNDJSON(t *testing.T) {
+	securities := testSecurities()
+	lines := strings.Split(strings.TrimSuffix(string(exportAll(t, "ndjson", securities)), "\n"), "\n")
+	if len(lines) != len(securities) {
+		t.Fatalf("Got %d lines, want %d", len(lines), len(securities))
+	}
+	for i, s := range securities {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(lines[i]), &record); err != nil {
+			t.Fatalf("%s: %s", lines[i], err)
+		}
+		for _, f := range fields {
+			want, _ := s.Field(f)
+			v, ok := record[f]
+			switch {
+			case len(want) == 0:
+				if ok {
+					t.Errorf("%s: got %s %v, want it omitted", s.CUSIP, f, v)
+				}
+			case f == "Coupon":
+				if got, _ := v.(float64); strconv.FormatFloat(got, 'f', -1, 64) != want {
+					t.Errorf("%s: got Coupon %v, want %s", s.CUSIP, v, want)
+				}
+			default:
+				if v != want {
+					t.Errorf("%s: got %s %v, want %q", s.CUSIP, f, v, want)
+				}
+			}
+		}
+	}
+}
+
+func TestCSV(t *testing.T) {
+	securities := testSecurities()
+	for f, comma := range map[string]rune{"csv": ',', "psv": '|'} {
+		r := csv.NewReader(bytes.NewReader(exportAll(t, f, securities)))
+		r.Comma = comma
+		rows, err := r.ReadAll()
+		if err != nil {
+			t.Fatalf("%s: %s", f, err)
+		}
+		if len(rows) != len(securities)+1 {
+			t.Fatalf("%s: got %d rows, want a header and %d securities", f, len(rows), len(securities))
+		}
+		if strings.Join(rows[0], ",") != strings.Join(fields, ",") {
+			t.Errorf("%s: got header %v, want %v", f, rows[0], fields)
+		}
+		for i, s := range securities {
+			for j, name := range fields {
+				if want, _ := s.Field(name); rows[i+1][j] != want {
+					t.Errorf("%s: %s got %s %q, want %q", f, s.CUSIP, name, rows[i+1][j], want)
+				}
+			}
+		}
+	}
+}
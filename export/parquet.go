@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/golang/snappy"
+)
+
+// A minimal Parquet writer: flat schema of optional UTF8 and DOUBLE columns,
+// PLAIN-encoded values, RLE definition levels and snappy-compressed v1 data pages,
+// one page per column chunk.
+
+const parquetMagic = "PAR1"
+
+// Parquet enum values from parquet.thrift
+const (
+	ptDouble    = 5
+	ptByteArray = 6
+
+	repOptional = 1
+	convUTF8    = 0
+
+	encPlain = 0
+	encRLE   = 3
+
+	codecSnappy = 1
+	pageData    = 0
+)
+
+// parquetColumn accumulates the values of one column in the current row group
+type parquetColumn struct {
+	name    string
+	double  bool
+	defined []bool
+	values  bytes.Buffer
+	// totals across row groups, for the footer
+	chunks []columnChunk
+}
+
+type columnChunk struct {
+	offset       int64
+	values       int64
+	uncompressed int64
+	compressed   int64
+}
+
+type rowGroup struct {
+	rows  int64
+	bytes int64
+}
+
+// parquetWriter writes rows to w, flushing a row group every groupSize rows
+type parquetWriter struct {
+	w         io.Writer
+	offset    int64
+	columns   []*parquetColumn
+	rows      int64
+	groupSize int64
+	groups    []rowGroup
+}
+
+func newParquetWriter(w io.Writer, names []string, doubles map[string]bool) (pw *parquetWriter, err error) {
+	pw = &parquetWriter{w: w, groupSize: 100000}
+	for _, n := range names {
+		pw.columns = append(pw.columns, &parquetColumn{name: n, double: doubles[n]})
+	}
+	err = pw.write([]byte(parquetMagic))
+	return
+}
+
+func (pw *parquetWriter) write(p []byte) error {
+	n, err := pw.w.Write(p)
+	pw.offset += int64(n)
+	return err
+}
+
+// Write appends a row.  Values must be string or float64, or nil for null.
+func (pw *parquetWriter) Write(row []interface{}) error {
+	for i, c := range pw.columns {
+		switch v := row[i].(type) {
+		case nil:
+			c.defined = append(c.defined, false)
+		case float64:
+			c.defined = append(c.defined, true)
+			var b [8]byte
+			binary.LittleEndian.PutUint64(b[:], math.Float64bits(v))
+			c.values.Write(b[:])
+		case string:
+			c.defined = append(c.defined, true)
+			var b [4]byte
+			binary.LittleEndian.PutUint32(b[:], uint32(len(v)))
+			c.values.Write(b[:])
+			c.values.WriteString(v)
+		}
+	}
+	pw.rows++
+	if pw.rows%pw.groupSize == 0 {
+		return pw.flush()
+	}
+	return nil
+}
+
+// flush writes the buffered rows as a row group
+func (pw *parquetWriter) flush() error {
+	if len(pw.columns) == 0 || len(pw.columns[0].defined) == 0 {
+		return nil
+	}
+	g := rowGroup{rows: int64(len(pw.columns[0].defined))}
+	for _, c := range pw.columns {
+		levels := rleBooleans(c.defined)
+		page := make([]byte, 4, 4+len(levels)+c.values.Len())
+		binary.LittleEndian.PutUint32(page, uint32(len(levels)))
+		page = append(page, levels...)
+		page = append(page, c.values.Bytes()...)
+		compressed := snappy.Encode(nil, page)
+
+		t := &thriftWriter{}
+		t.i32(1, pageData)
+		t.i32(2, int32(len(page)))
+		t.i32(3, int32(len(compressed)))
+		t.beginStruct(5)
+		t.i32(1, int32(len(c.defined)))
+		t.i32(2, encPlain)
+		t.i32(3, encRLE)
+		t.i32(4, encRLE)
+		t.endStruct()
+		t.stop()
+
+		chunk := columnChunk{
+			offset:       pw.offset,
+			values:       int64(len(c.defined)),
+			uncompressed: int64(t.buf.Len() + len(page)),
+			compressed:   int64(t.buf.Len() + len(compressed)),
+		}
+		if err := pw.write(t.buf.Bytes()); err != nil {
+			return err
+		}
+		if err := pw.write(compressed); err != nil {
+			return err
+		}
+		c.chunks = append(c.chunks, chunk)
+		g.bytes += chunk.uncompressed
+		c.defined = c.defined[:0]
+		c.values.Reset()
+	}
+	pw.groups = append(pw.groups, g)
+	return nil
+}
+
+// Close flushes buffered rows and writes the file footer
+func (pw *parquetWriter) Close() error {
+	if err := pw.flush(); err != nil {
+		return err
+	}
+	t := &thriftWriter{}
+	t.i32(1, 1)
+	t.beginList(2, thriftStruct, len(pw.columns)+1)
+	t.beginElement()
+	t.binary(4, "schema")
+	t.i32(5, int32(len(pw.columns)))
+	t.endStruct()
+	for _, c := range pw.columns {
+		t.beginElement()
+		if c.double {
+			t.i32(1, ptDouble)
+		} else {
+			t.i32(1, ptByteArray)
+		}
+		t.i32(3, repOptional)
+		t.binary(4, c.name)
+		if !c.double {
+			t.i32(6, convUTF8)
+		}
+		t.endStruct()
+	}
+	t.i64(3, pw.rows)
+	t.beginList(4, thriftStruct, len(pw.groups))
+	for i, g := range pw.groups {
+		t.beginElement()
+		t.beginList(1, thriftStruct, len(pw.columns))
+		for _, c := range pw.columns {
+			chunk := c.chunks[i]
+			t.beginElement()
+			t.i64(2, chunk.offset)
+			t.beginStruct(3)
+			if c.double {
+				t.i32(1, ptDouble)
+			} else {
+				t.i32(1, ptByteArray)
+			}
+			t.beginList(2, thriftI32, 2)
+			t.varint(zigzag(encPlain))
+			t.varint(zigzag(encRLE))
+			t.beginList(3, thriftBinary, 1)
+			t.varint(uint64(len(c.name)))
+			t.buf.WriteString(c.name)
+			t.i32(4, codecSnappy)
+			t.i64(5, chunk.values)
+			t.i64(6, chunk.uncompressed)
+			t.i64(7, chunk.compressed)
+			t.i64(9, chunk.offset)
+			t.endStruct()
+			t.endStruct()
+		}
+		t.i64(2, g.bytes)
+		t.i64(3, g.rows)
+		t.endStruct()
+	}
+	t.binary(6, "fast_lem export")
+	t.stop()
+	if err := pw.write(t.buf.Bytes()); err != nil {
+		return err
+	}
+	var n [4]byte
+	binary.LittleEndian.PutUint32(n[:], uint32(t.buf.Len()))
+	if err := pw.write(n[:]); err != nil {
+		return err
+	}
+	return pw.write([]byte(parquetMagic))
+}
+
+// rleBooleans encodes definition levels of bit width 1 as RLE runs
+func rleBooleans(levels []bool) []byte {
+	var out []byte
+	var n [binary.MaxVarintLen64]byte
+	for i := 0; i < len(levels); {
+		j := i + 1
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		out = append(out, n[:binary.PutUvarint(n[:], uint64(j-i)<<1)]...)
+		if levels[i] {
+			out = append(out, 1)
+		} else {
+			out = append(out, 0)
+		}
+		i = j
+	}
+	return out
+}
+
+// Thrift compact protocol type codes
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes the subset of the Thrift compact protocol Parquet metadata needs
+type thriftWriter struct {
+	buf   bytes.Buffer
+	last  int16
+	stack []int16
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	t.buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	if delta := id - t.last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(zigzag(int64(id)))
+	}
+	t.last = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(zigzag(v))
+}
+
+func (t *thriftWriter) binary(id int16, v string) {
+	t.field(id, thriftBinary)
+	t.varint(uint64(len(v)))
+	t.buf.WriteString(v)
+}
+
+func (t *thriftWriter) beginList(id int16, elem byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elem)
+	} else {
+		t.buf.WriteByte(0xf0 | elem)
+		t.varint(uint64(size))
+	}
+}
+
+func (t *thriftWriter) beginStruct(id int16) {
+	t.field(id, thriftStruct)
+	t.beginElement()
+}
+
+// beginElement starts a struct that is a list element rather than a field
+func (t *thriftWriter) beginElement() {
+	t.stack = append(t.stack, t.last)
+	t.last = 0
+}
+
+func (t *thriftWriter) endStruct() {
+	t.stop()
+	t.last = t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+}
+
+func (t *thriftWriter) stop() {
+	t.buf.WriteByte(0)
+}
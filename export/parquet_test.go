@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/golang/snappy"
+)
+
+// thriftReader decodes the Thrift compact protocol into generic values: structs as
+// maps from field id to value, lists as slices, integers as int64 and binary as []byte.
+// It is written from the protocol spec rather than from thriftWriter, so the tests
+// below check the writer against an independent reading of the format.
+type thriftReader struct {
+	r   *bytes.Reader
+	err error
+}
+
+func (t *thriftReader) fail(format string, args ...interface{}) {
+	if t.err == nil {
+		t.err = fmt.Errorf(format, args...)
+	}
+}
+
+func (t *thriftReader) byte() byte {
+	b, err := t.r.ReadByte()
+	if err != nil {
+		t.fail("read byte: %s", err)
+	}
+	return b
+}
+
+func (t *thriftReader) uvarint() uint64 {
+	v, err := binary.ReadUvarint(t.r)
+	if err != nil {
+		t.fail("read varint: %s", err)
+	}
+	return v
+}
+
+func (t *thriftReader) zigzag() int64 {
+	v := t.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (t *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case 1, 2:
+		return typ == 1
+	case 3:
+		return int64(int8(t.byte()))
+	case 4, 5, 6:
+		return t.zigzag()
+	case 7:
+		var b [8]byte
+		t.r.Read(b[:])
+		return math.Float64frombits(binary.LittleEndian.Uint64(b[:]))
+	case 8:
+		b := make([]byte, t.uvarint())
+		if n, _ := t.r.Read(b); n != len(b) {
+			t.fail("short binary: %d of %d bytes", n, len(b))
+		}
+		return b
+	case 9, 10:
+		h := t.byte()
+		size, elem := uint64(h>>4), h&0x0f
+		if size == 15 {
+			size = t.uvarint()
+		}
+		list := []interface{}{}
+		for i := uint64(0); i < size && t.err == nil; i++ {
+			if elem == 1 || elem == 2 {
+				list = append(list, t.byte() == 1)
+				continue
+			}
+			list = append(list, t.value(elem))
+		}
+		return list
+	case 12:
+		return t.structure()
+	}
+	t.fail("unsupported type %d", typ)
+	return nil
+}
+
+func (t *thriftReader) structure() map[int16]interface{} {
+	fields := make(map[int16]interface{})
+	var id int16
+	for t.err == nil {
+		h := t.byte()
+		if h == 0 {
+			break
+		}
+		if delta := int16(h >> 4); delta != 0 {
+			id += delta
+		} else {
+			id = int16(t.zigzag())
+		}
+		fields[id] = t.value(h & 0x0f)
+	}
+	return fields
+}
+
+// readLevels decodes n definition levels of bit width 1 in the RLE/bit-packed hybrid encoding
+func readLevels(b []byte, n int) ([]bool, error) {
+	r := bytes.NewReader(b)
+	var levels []bool
+	for len(levels) < n {
+		h, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if h&1 == 0 {
+			v, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			for i := uint64(0); i < h>>1; i++ {
+				levels = append(levels, v == 1)
+			}
+			continue
+		}
+		for g := uint64(0); g < h>>1; g++ {
+			v, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			for bit := uint(0); bit < 8; bit++ {
+				levels = append(levels, v>>bit&1 == 1)
+			}
+		}
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("%d bytes after %d levels", r.Len(), n)
+	}
+	return levels[:n], nil
+}
+
+// readColumn decodes the single data page of a column chunk
+func readColumn(data []byte, meta map[int16]interface{}) ([]interface{}, error) {
+	offset := meta[9].(int64)
+	t := &thriftReader{r: bytes.NewReader(data[offset:])}
+	header := t.structure()
+	if t.err != nil {
+		return nil, t.err
+	}
+	headerLen := int64(len(data[offset:]) - t.r.Len())
+	if header[1].(int64) != pageData {
+		return nil, fmt.Errorf("got page type %d, want a data page", header[1])
+	}
+	compressed := header[3].(int64)
+	if total := meta[7].(int64); total != headerLen+compressed {
+		return nil, fmt.Errorf("got total_compressed_size %d, want %d", total, headerLen+compressed)
+	}
+	page, err := snappy.Decode(nil, data[offset+headerLen:offset+headerLen+compressed])
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(page)) != header[2].(int64) || meta[6].(int64) != headerLen+int64(len(page)) {
+		return nil, fmt.Errorf("got %d uncompressed bytes, want %d", len(page), header[2])
+	}
+	dataPage := header[5].(map[int16]interface{})
+	n := int(dataPage[1].(int64))
+	if meta[5].(int64) != int64(n) {
+		return nil, fmt.Errorf("got %d values in the page, want %d", n, meta[5])
+	}
+	levelsLen := binary.LittleEndian.Uint32(page)
+	defined, err := readLevels(page[4:4+levelsLen], n)
+	if err != nil {
+		return nil, err
+	}
+	values := page[4+levelsLen:]
+	column := make([]interface{}, n)
+	for i, d := range defined {
+		switch {
+		case !d:
+		case meta[1].(int64) == ptDouble:
+			column[i] = math.Float64frombits(binary.LittleEndian.Uint64(values))
+			values = values[8:]
+		default:
+			size := binary.LittleEndian.Uint32(values)
+			column[i] = string(values[4 : 4+size])
+			values = values[4+size:]
+		}
+	}
+	if len(values) != 0 {
+		return nil, fmt.Errorf("%d bytes after %d values", len(values), n)
+	}
+	return column, nil
+}
+
+// readParquet returns the column names and rows of a file written by parquetWriter
+func readParquet(data []byte) (names []string, rows [][]interface{}, err error) {
+	if !bytes.HasPrefix(data, []byte(parquetMagic)) || !bytes.HasSuffix(data, []byte(parquetMagic)) {
+		return nil, nil, fmt.Errorf("missing %s magic", parquetMagic)
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	t := &thriftReader{r: bytes.NewReader(data[len(data)-8-footerLen : len(data)-8])}
+	meta := t.structure()
+	if t.err != nil {
+		return nil, nil, t.err
+	}
+	if t.r.Len() != 0 {
+		return nil, nil, fmt.Errorf("%d bytes after the footer", t.r.Len())
+	}
+	schema := meta[2].([]interface{})
+	if root := schema[0].(map[int16]interface{}); root[5].(int64) != int64(len(schema)-1) {
+		return nil, nil, fmt.Errorf("got %d children of the root, want %d", root[5], len(schema)-1)
+	}
+	types := make(map[string]int64)
+	for _, e := range schema[1:] {
+		element := e.(map[int16]interface{})
+		name := string(element[4].([]byte))
+		if element[3].(int64) != repOptional {
+			return nil, nil, fmt.Errorf("%s: got repetition %d, want optional", name, element[3])
+		}
+		names = append(names, name)
+		types[name] = element[1].(int64)
+	}
+	for _, g := range meta[4].([]interface{}) {
+		group := g.(map[int16]interface{})
+		var columns [][]interface{}
+		for i, c := range group[1].([]interface{}) {
+			chunk := c.(map[int16]interface{})
+			meta := chunk[3].(map[int16]interface{})
+			if path := meta[3].([]interface{}); len(path) != 1 || string(path[0].([]byte)) != names[i] {
+				return nil, nil, fmt.Errorf("got path %q for column %s", path, names[i])
+			}
+			if meta[1].(int64) != types[names[i]] || meta[4].(int64) != codecSnappy {
+				return nil, nil, fmt.Errorf("%s: got type %d and codec %d", names[i], meta[1], meta[4])
+			}
+			column, err := readColumn(data, meta)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %s", names[i], err)
+			}
+			if int64(len(column)) != group[3].(int64) {
+				return nil, nil, fmt.Errorf("%s: got %d values in a group of %d rows", names[i], len(column), group[3])
+			}
+			columns = append(columns, column)
+		}
+		for r := range columns[0] {
+			row := make([]interface{}, len(columns))
+			for i := range columns {
+				row[i] = columns[i][r]
+			}
+			rows = append(rows, row)
+		}
+	}
+	if int64(len(rows)) != meta[3].(int64) {
+		return nil, nil, fmt.Errorf("got %d rows in row groups, want num_rows %d", len(rows), meta[3])
+	}
+	return
+}
+
+func TestParquet(t *testing.T) {
+	securities := testSecurities()
+	names, rows, err := readParquet(exportAll(t, "parquet", securities))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, fields) {
+		t.Errorf("Got columns %v, want %v", names, fields)
+	}
+	if len(rows) != len(securities) {
+		t.Fatalf("Got %d rows, want %d", len(rows), len(securities))
+	}
+	for i, s := range securities {
+		for j, f := range fields {
+			want, _ := s.Field(f)
+			var got string
+			switch v := rows[i][j].(type) {
+			case nil:
+			case float64:
+				got = strconv.FormatFloat(v, 'f', -1, 64)
+			case string:
+				got = v
+				if len(v) == 0 {
+					t.Errorf("%s: got an empty %s, want null", s.CUSIP, f)
+				}
+			}
+			if got != want {
+				t.Errorf("%s: got %s %v, want %q", s.CUSIP, f, rows[i][j], want)
+			}
+		}
+	}
+}
+
+func TestParquetRowGroups(t *testing.T) {
+	buf := new(bytes.Buffer)
+	pw, err := newParquetWriter(buf, []string{"Name", "Coupon"}, map[string]bool{"Coupon": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pw.groupSize = 7
+	var want [][]interface{}
+	for i := 0; i < 40; i++ {
+		// long runs of nulls and values, and values long enough for multi-byte varints
+		row := []interface{}{strings.Repeat(strconv.Itoa(i), 50), float64(i) / 8}
+		if i >= 10 && i < 30 {
+			row[0] = nil
+		}
+		if i%3 == 0 {
+			row[1] = nil
+		}
+		want = append(want, row)
+		if err = pw.Write(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = pw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(pw.groups) != 6 {
+		t.Errorf("Got %d row groups, want 6", len(pw.groups))
+	}
+	_, rows, err := readParquet(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("Got rows %v, want %v", rows, want)
+	}
+}
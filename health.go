@@ -0,0 +1,159 @@
+package fast_lem
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Health tracks whether a server is alive and ready to serve lookups from a Swappable
+type Health struct {
+	storage *Swappable
+	checks  *CheckSuite
+	started time.Time
+
+	mu       sync.Mutex
+	loaded   time.Time
+	loadErr  error
+	failedAt time.Time
+	// report is the outcome of the checks against the data installed by the swap
+	// numbered reportGen
+	report    *CheckReport
+	reportGen uint64
+}
+
+// NewHealth returns the Health of a server serving lookups from storage.  The server
+// is only ready while checks pass against the data being served.
+func NewHealth(storage *Swappable, checks *CheckSuite) *Health {
+	return &Health{storage: storage, checks: checks, started: time.Now()}
+}
+
+// Loaded records that new data has been swapped in
+func (h *Health) Loaded() {
+	h.mu.Lock()
+	h.loaded = time.Now()
+	h.loadErr = nil
+	h.mu.Unlock()
+}
+
+// LoadFailed records a failed attempt to load new data.  Data already being served
+// continues to be served.
+func (h *Health) LoadFailed(err error) {
+	h.mu.Lock()
+	h.loadErr = err
+	h.failedAt = time.Now()
+	h.mu.Unlock()
+}
+
+// Readiness is the response of the readiness endpoint
+type Readiness struct {
+	Ready bool
+	// Reasons explains why the server is not ready
+	Reasons []string `json:",omitempty"`
+}
+
+// Ready reports whether the server can serve lookups: data has been loaded, no swap
+// is waiting for lookups in flight, and the checks pass against the data.  The checks
+// run once for each swap.
+func (h *Health) Ready() *Readiness {
+	r := &Readiness{}
+	switch {
+	case h.storage.Swapping():
+		r.Reasons = append(r.Reasons, "swapping in new data")
+	case !h.storage.Loaded():
+		reason := "no data loaded"
+		h.mu.Lock()
+		if h.loadErr != nil {
+			reason += ": " + h.loadErr.Error()
+		}
+		h.mu.Unlock()
+		r.Reasons = append(r.Reasons, reason)
+	case h.checks != nil:
+		r.Reasons = append(r.Reasons, h.checkReport().Failures...)
+	}
+	r.Ready = len(r.Reasons) == 0
+	return r
+}
+
+// checkReport returns the outcome of the checks against the data being served,
+// running them if they have not run since the last swap
+func (h *Health) checkReport() (report *CheckReport) {
+	h.storage.withCurrent(func(g Getter, generation uint64) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if h.report == nil || h.reportGen != generation {
+			h.report, h.reportGen = h.checks.Check(g), generation
+		}
+		report = h.report
+	})
+	return
+}
+
+// HealthzHandler responds OK for as long as the process can serve HTTP
+func (h *Health) HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("ok\n"))
+}
+
+// ReadyzHandler responds with the server's Readiness, with status 503 if it is not
+// ready
+func (h *Health) ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	readiness := h.Ready()
+	js, err := json.Marshal(readiness)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if !readiness.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	w.Write(js)
+}
+
+// BuildInfo identifies the running server
+type BuildInfo struct {
+	Version   string
+	GoVersion string
+	Started   time.Time
+}
+
+// Info describes a running server and the data it serves.  The load metadata, if
+// any, is inlined.
+type Info struct {
+	*Metadata
+	// Records counts the records held, keyed by bucket name
+	Records map[string]int `json:",omitempty"`
+	Build   BuildInfo
+	// Loaded is when the data being served was swapped in
+	Loaded *time.Time `json:",omitempty"`
+	// LastLoadError describes the most recent failed load, if it was not followed by
+	// a successful one
+	LastLoadError string     `json:",omitempty"`
+	LastLoadFail  *time.Time `json:",omitempty"`
+}
+
+// info fills in the server's build and load history
+func (h *Health) info(i *Info) {
+	if h == nil {
+		return
+	}
+	i.Build.Started = h.started
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.loaded.IsZero() {
+		loaded := h.loaded
+		i.Loaded = &loaded
+	}
+	if h.loadErr != nil {
+		failed := h.failedAt
+		i.LastLoadError = h.loadErr.Error()
+		i.LastLoadFail = &failed
+	}
+}
+
+func newInfo() *Info {
+	return &Info{Build: BuildInfo{Version: Version, GoVersion: runtime.Version()}}
+}
@@ -0,0 +1,91 @@
+package fast_lem
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReadiness(t *testing.T) {
+	s := testSecurities()
+	suite := &CheckSuite{Expect: []Expectation{{Key: s[0].CUSIP, Fields: map[string]string{"LegalEntityId": s[0].LegalEntityID}}}}
+	storage := NewSwappable(nil)
+	health := NewHealth(storage, suite)
+	health.LoadFailed(errors.New("no such file"))
+	w := httptest.NewRecorder()
+	health.ReadyzHandler(w, httptest.NewRequest("GET", "/readyz", nil))
+	if w.Code != 503 || w.Body.String() != `{"Ready":false,"Reasons":["no data loaded: no such file"]}` {
+		t.Errorf("Got %d %s before loading, want 503", w.Code, w.Body)
+	}
+	storage.Swap(mapGetter{})
+	if r := health.Ready(); r.Ready || len(r.Reasons) != 1 {
+		t.Errorf("Got %+v with failing checks, want one reason", r)
+	}
+	storage.Swap(testMaster(t))
+	health.Loaded()
+	if r := health.Ready(); !r.Ready {
+		t.Errorf("Got %+v, want ready", r)
+	}
+}
+
+func TestInfoHandler(t *testing.T) {
+	m := testMaster(t)
+	m.Metadata = &Metadata{Rows: 3}
+	storage := NewSwappable(m)
+	health := NewHealth(storage, nil)
+	health.Loaded()
+	server := Server{Getter: NewCache(storage, 10), Health: health}
+	w := httptest.NewRecorder()
+	server.InfoHandler(w, httptest.NewRequest("GET", "/info", nil))
+	var info struct {
+		Rows    int
+		Records map[string]int
+		Build   BuildInfo
+		Loaded  string
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &info); err != nil {
+		t.Fatal(err, w.Body)
+	}
+	if info.Rows != 3 || info.Records[DetailsBucket] != 3 || info.Records[SedolBucket] != 2 || info.Build.Version != Version || len(info.Loaded) == 0 {
+		t.Errorf("Unexpected info %s", w.Body)
+	}
+}
+
+// countingRecords counts the calls to RecordCounts
+type countingRecords struct {
+	countingGetter
+	calls int
+}
+
+func (cr *countingRecords) RecordCounts() (map[string]int, error) {
+	cr.calls++
+	return map[string]int{DetailsBucket: len(cr.mapGetter)}, nil
+}
+
+func TestReadinessOncePerSwap(t *testing.T) {
+	s := testSecurities()
+	suite := &CheckSuite{Expect: []Expectation{{Key: s[0].CUSIP, Fields: map[string]string{"LegalEntityId": s[0].LegalEntityID}}}}
+	first := &countingRecords{countingGetter: countingGetter{mapGetter: mapGetter{s[0].CUSIP: s[0]}}}
+	storage := NewSwappable(first)
+	health := NewHealth(storage, suite)
+	for i := 0; i < 3; i++ {
+		if r := health.Ready(); !r.Ready {
+			t.Fatalf("Got %+v, want ready", r)
+		}
+		if counts, _ := storage.RecordCounts(); counts[DetailsBucket] != 1 {
+			t.Fatalf("Got counts %v, want 1 security", counts)
+		}
+	}
+	if first.keys != 1 || first.calls != 1 {
+		t.Errorf("Checked %d keys and counted records %d times across 3 probes, want 1 and 1", first.keys, first.calls)
+	}
+	second := &countingRecords{countingGetter: countingGetter{mapGetter: mapGetter{}}}
+	storage.Swap(second)
+	if r := health.Ready(); r.Ready {
+		t.Errorf("Got %+v after swapping in data that fails the checks, want unready", r)
+	}
+	if counts, _ := storage.RecordCounts(); counts[DetailsBucket] != 0 || second.calls != 1 {
+		t.Errorf("Got counts %v after %d calls, want them recounted once after the swap", counts, second.calls)
+	}
+}
@@ -0,0 +1,402 @@
+package fast_lem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/boltdb/bolt"
+)
+
+var (
+	ErrNoHierarchy = errors.New("no corporate hierarchy loaded")
+)
+
+// Entity is a FactSet legal entity and its immediate parent in the corporate structure
+type Entity struct {
+	ID   string
+	Name string `json:",omitempty"`
+	// Parent is empty for an ultimate parent
+	Parent string `json:",omitempty"`
+}
+
+// EntityResolver is implemented by Getters that know the corporate structure above and
+// below the legal entities of their Securities
+type EntityResolver interface {
+	// ParentChain returns the entity with id followed by each of its parents in turn,
+	// ending with its ultimate parent
+	ParentChain(ctx context.Context, id string) ([]Entity, error)
+	// Descendants returns id and every entity beneath it, sorted
+	Descendants(ctx context.Context, id string) ([]string, error)
+}
+
+// ParentChain returns the parent chain of the entity with id from g, which must be an
+// EntityResolver
+func ParentChain(ctx context.Context, g Getter, id string) ([]Entity, error) {
+	if er, ok := g.(EntityResolver); ok {
+		return er.ParentChain(ctx, id)
+	}
+	return nil, ErrNoHierarchy
+}
+
+// Descendants returns id and every entity beneath it from g, which must be an
+// EntityResolver
+func Descendants(ctx context.Context, g Getter, id string) ([]string, error) {
+	if er, ok := g.(EntityResolver); ok {
+		return er.Descendants(ctx, id)
+	}
+	return nil, ErrNoHierarchy
+}
+
+// parentChain follows the parents returned by get from id, stopping at the ultimate
+// parent or at the first entity seen twice if the structure has a cycle.  An entity
+// get does not know is reported with only its ID.
+func parentChain(id string, get func(id string) (*Entity, error)) (chain []Entity, err error) {
+	seen := make(map[string]bool)
+	for len(id) > 0 && !seen[id] {
+		seen[id] = true
+		var e *Entity
+		e, err = get(id)
+		if err != nil {
+			return nil, err
+		}
+		if e == nil {
+			e = &Entity{ID: id}
+		}
+		chain = append(chain, *e)
+		id = e.Parent
+	}
+	return
+}
+
+// descendants walks the children returned by children breadth first from id
+func descendants(ctx context.Context, id string, children func(id string) ([]string, error)) ([]string, error) {
+	seen := map[string]bool{id: true}
+	ids := []string{id}
+	for i := 0; i < len(ids); i++ {
+		if i%1000 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		kids, err := children(ids[i])
+		if err != nil {
+			return nil, err
+		}
+		for _, kid := range kids {
+			if !seen[kid] {
+				seen[kid] = true
+				ids = append(ids, kid)
+			}
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Hierarchy is the corporate structure of the legal entities in a SecurityMaster
+type Hierarchy struct {
+	entities map[string]*Entity
+	children map[string][]string
+}
+
+// NewHierarchy returns the structure formed by entities.  Later entities replace
+// earlier ones with the same ID.
+func NewHierarchy(entities []*Entity) *Hierarchy {
+	h := &Hierarchy{entities: make(map[string]*Entity, len(entities)), children: make(map[string][]string)}
+	for _, e := range entities {
+		h.entities[e.ID] = e
+	}
+	for _, e := range h.entities {
+		if len(e.Parent) > 0 {
+			h.children[e.Parent] = append(h.children[e.Parent], e.ID)
+		}
+	}
+	return h
+}
+
+// Len returns the number of entities in the structure
+func (h *Hierarchy) Len() int {
+	return len(h.entities)
+}
+
+// Entity returns the entity with id
+func (h *Hierarchy) Entity(id string) (*Entity, bool) {
+	e, ok := h.entities[id]
+	return e, ok
+}
+
+// Entities returns every entity in the structure, sorted by ID
+func (h *Hierarchy) Entities() []*Entity {
+	entities := make([]*Entity, 0, len(h.entities))
+	for _, e := range h.entities {
+		entities = append(entities, e)
+	}
+	sort.Slice(entities, func(i, j int) bool { return entities[i].ID < entities[j].ID })
+	return entities
+}
+
+// ParentChain returns the entity with id followed by each of its parents in turn
+func (h *Hierarchy) ParentChain(id string) []Entity {
+	chain, _ := parentChain(id, func(id string) (*Entity, error) { return h.entities[id], nil })
+	return chain
+}
+
+// Descendants returns id and every entity beneath it, sorted
+func (h *Hierarchy) Descendants(id string) []string {
+	ids, _ := descendants(context.Background(), id, func(id string) ([]string, error) { return h.children[id], nil })
+	return ids
+}
+
+// ParentChain returns the entity with id followed by each of its parents in turn
+func (m *SecurityMaster) ParentChain(ctx context.Context, id string) ([]Entity, error) {
+	if m.Hierarchy == nil {
+		return nil, ErrNoHierarchy
+	}
+	return m.Hierarchy.ParentChain(id), nil
+}
+
+// Descendants returns id and every entity beneath it, sorted
+func (m *SecurityMaster) Descendants(ctx context.Context, id string) ([]string, error) {
+	if m.Hierarchy == nil {
+		return nil, ErrNoHierarchy
+	}
+	return m.Hierarchy.Descendants(id), nil
+}
+
+// ParentChain returns the entity with id followed by each of its parents in turn
+func (bp *boltPersistance) ParentChain(ctx context.Context, id string) (chain []Entity, err error) {
+	err = bp.view(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(EntityBucket))
+		if b == nil {
+			return ErrNoHierarchy
+		}
+		chain, err = parentChain(id, func(id string) (*Entity, error) { return getEntity(b, id) })
+		return err
+	})
+	return
+}
+
+// Descendants returns id and every entity beneath it, sorted
+func (bp *boltPersistance) Descendants(ctx context.Context, id string) (ids []string, err error) {
+	err = bp.view(func(tx *bolt.Tx) error {
+		ids, err = entityDescendants(ctx, tx, id)
+		return err
+	})
+	return
+}
+
+func getEntity(b *bolt.Bucket, id string) (*Entity, error) {
+	data := b.Get([]byte(id))
+	if data == nil {
+		return nil, nil
+	}
+	e := &Entity{}
+	if err := json.Unmarshal(data, e); err != nil {
+		return nil, fmt.Errorf("entity %s: %s", id, err)
+	}
+	return e, nil
+}
+
+func entityDescendants(ctx context.Context, tx *bolt.Tx, id string) ([]string, error) {
+	b := tx.Bucket([]byte(EntityChildrenBucket))
+	if b == nil {
+		return nil, ErrNoHierarchy
+	}
+	return descendants(ctx, id, func(id string) ([]string, error) {
+		prefix := []byte(id + "\x00")
+		return scanIndex(b, prefix, prefix, nil), nil
+	})
+}
+
+// WriteEntities records the corporate structure formed by entities, replacing any
+// structure already in db
+func WriteEntities(db *bolt.DB, entities []*Entity) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		for _, name := range []string{EntityBucket, EntityChildrenBucket} {
+			if tx.Bucket([]byte(name)) != nil {
+				if err := tx.DeleteBucket([]byte(name)); err != nil {
+					return err
+				}
+			}
+		}
+		eb, err := tx.CreateBucket([]byte(EntityBucket))
+		if err != nil {
+			return fmt.Errorf("create bucket: %s", err)
+		}
+		cb, err := tx.CreateBucket([]byte(EntityChildrenBucket))
+		if err != nil {
+			return fmt.Errorf("create bucket: %s", err)
+		}
+		for _, e := range entities {
+			data, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			if err = eb.Put([]byte(e.ID), data); err != nil {
+				return err
+			}
+			if len(e.Parent) > 0 {
+				if err = cb.Put(indexKey(e.Parent, e.ID), []byte{}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// ReadHierarchy returns the corporate structure recorded in db by WriteEntities, or
+// ErrNoHierarchy if db has none
+func ReadHierarchy(db *bolt.DB) (h *Hierarchy, err error) {
+	err = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(EntityBucket))
+		if b == nil {
+			return ErrNoHierarchy
+		}
+		var entities []*Entity
+		err := b.ForEach(func(k, v []byte) error {
+			e := &Entity{}
+			if err := json.Unmarshal(v, e); err != nil {
+				return fmt.Errorf("entity %s: %s", k, err)
+			}
+			entities = append(entities, e)
+			return nil
+		})
+		h = NewHierarchy(entities)
+		return err
+	})
+	return
+}
+
+// ReadEntityStructure reads a FactSet entity structure file, a pipe-delimited file
+// with a header naming the columns FACTSET_ENTITY_ID and FACTSET_PARENT_ENTITY_ID, and
+// optionally ENTITY_PROPER_NAME.  An entity that is its own parent is an ultimate
+// parent.
+func ReadEntityStructure(source io.Reader) ([]*Entity, error) {
+	r := NewReader(source)
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("entity structure header: %s", err)
+	}
+	cols := map[string]int{"FACTSET_ENTITY_ID": -1, "FACTSET_PARENT_ENTITY_ID": -1, "ENTITY_PROPER_NAME": -1}
+	for i, name := range header {
+		if _, ok := cols[strings.ToUpper(name)]; ok {
+			cols[strings.ToUpper(name)] = i
+		}
+	}
+	for _, required := range []string{"FACTSET_ENTITY_ID", "FACTSET_PARENT_ENTITY_ID"} {
+		if cols[required] < 0 {
+			return nil, fmt.Errorf("entity structure has no %s column", required)
+		}
+	}
+	var entities []*Entity
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			return entities, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("entity structure: %s", err)
+		}
+		e := &Entity{ID: row[cols["FACTSET_ENTITY_ID"]], Parent: row[cols["FACTSET_PARENT_ENTITY_ID"]]}
+		if len(e.ID) == 0 {
+			continue
+		}
+		if e.Parent == e.ID {
+			e.Parent = ""
+		}
+		if i := cols["ENTITY_PROPER_NAME"]; i >= 0 {
+			e.Name = row[i]
+		}
+		entities = append(entities, e)
+	}
+}
+
+// HierarchyResponse is the corporate structure above a legal entity
+type HierarchyResponse struct {
+	// Key is the security identifier asked about, if any
+	Key    string `json:",omitempty"`
+	Entity string
+	// Chain starts with Entity and ends with its ultimate parent
+	Chain          []Entity
+	UltimateParent string
+}
+
+// HierarchyHandler responds to a GET request with the parent chain of the legal
+// entity given by the entity query parameter, or of the issuer of the security given
+// by the key parameter.  The securities under an ultimate parent are found by
+// searching with its ID as the entity filter.
+func (s Server) HierarchyHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	response := &HierarchyResponse{Key: q.Get("key"), Entity: q.Get("entity")}
+	if (len(response.Key) == 0) == (len(response.Entity) == 0) {
+		http.Error(w, "Expected either an entity or a key parameter", http.StatusBadRequest)
+		return
+	}
+	entitlement := EntitlementFrom(r.Context())
+	if entitlement.RestrictsFields() && !contains(entitlement.Fields, "LegalEntityId") {
+		http.Error(w, "Not entitled to LegalEntityId", http.StatusForbidden)
+		return
+	}
+	if len(response.Key) > 0 && !entitlement.AllowsType(IdentifierTypeOf(response.Key)) {
+		http.Error(w, "Not entitled to look up "+response.Key, http.StatusForbidden)
+		return
+	}
+	ctx := r.Context()
+	if s.LookupTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.LookupTimeout)
+		defer cancel()
+	}
+	var err error
+	if len(response.Key) > 0 {
+		var securities []*Security
+		securities, err = GetContext(ctx, s.Getter, response.Key)
+		if err == nil {
+			if len(securities[0].CUSIP) == 0 {
+				http.Error(w, "Security "+response.Key+" not found", http.StatusNotFound)
+				return
+			}
+			response.Entity = securities[0].LegalEntityID
+			if len(response.Entity) == 0 {
+				http.Error(w, "Security "+response.Key+" has no legal entity", http.StatusNotFound)
+				return
+			}
+		}
+	}
+	if err == nil {
+		response.Chain, err = ParentChain(ctx, s.Getter, response.Entity)
+	}
+	switch err {
+	case nil:
+	case context.DeadlineExceeded:
+		http.Error(w, "Lookup timed out after "+s.LookupTimeout.String(), http.StatusGatewayTimeout)
+		return
+	case context.Canceled:
+		// the client has gone away
+		return
+	case ErrNoHierarchy:
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	case ErrNotLoaded:
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	response.UltimateParent = response.Chain[len(response.Chain)-1].ID
+	js, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
@@ -0,0 +1,158 @@
+package fast_lem
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testEntityStructure = `"FACTSET_ENTITY_ID"|"FACTSET_PARENT_ENTITY_ID"|"FACTSET_ULTIMATE_PARENT_ENTITY_ID"|"ENTITY_PROPER_NAME"
+"8C7QCS-E"|"000XT9-E"|"0ULT00-E"|"Sub"
+"000XT9-E"|"0ULT00-E"|"0ULT00-E"|"Holdings"
+"0ULT00-E"|"0ULT00-E"|"0ULT00-E"|"Group"
+"0CYC01-E"|"0CYC02-E"|""|""
+"0CYC02-E"|"0CYC01-E"|""|""
+`
+
+func testEntities(t testing.TB) []*Entity {
+	entities, err := ReadEntityStructure(strings.NewReader(testEntityStructure))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entities
+}
+
+func TestReadEntityStructure(t *testing.T) {
+	entities := testEntities(t)
+	if len(entities) != 5 {
+		t.Fatalf("Got %d entities, want 5", len(entities))
+	}
+	if got, want := *entities[2], (Entity{ID: "0ULT00-E", Name: "Group"}); got != want {
+		t.Errorf("Got %+v, want an ultimate parent %+v", got, want)
+	}
+	_, err := ReadEntityStructure(strings.NewReader("\"FACTSET_ENTITY_ID\"\n\"0ULT00-E\"\n"))
+	if err == nil {
+		t.Error("Got no error for a file without parents")
+	}
+}
+
+func TestHierarchy(t *testing.T) {
+	storage, cleanup := testStorage(t)
+	defer cleanup()
+	db := storage.(*boltPersistance).db
+	if _, err := ParentChain(context.Background(), storage, "8C7QCS-E"); err != ErrNoHierarchy {
+		t.Errorf("Got %v before loading entities, want %v", err, ErrNoHierarchy)
+	}
+	if err := WriteEntities(db, testEntities(t)); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewSecurityMasterFromBolt(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	if err = m.WriteSnapshot(buf); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := ReadSnapshot(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, g := range []Getter{storage, m, snapshot} {
+		chain, err := ParentChain(context.Background(), g, "8C7QCS-E")
+		if err != nil {
+			t.Fatalf("%T: %s", g, err)
+		}
+		var ids []string
+		for _, e := range chain {
+			ids = append(ids, e.ID)
+		}
+		if want := []string{"8C7QCS-E", "000XT9-E", "0ULT00-E"}; !reflect.DeepEqual(ids, want) {
+			t.Errorf("%T: got chain %v, want %v", g, ids, want)
+		}
+		if chain, _ = ParentChain(context.Background(), g, "0CYC01-E"); len(chain) != 2 {
+			t.Errorf("%T: got chain %+v through a cycle, want 2 entities", g, chain)
+		}
+		ids, err = Descendants(context.Background(), g, "0ULT00-E")
+		if want := []string{"000XT9-E", "0ULT00-E", "8C7QCS-E"}; err != nil || !reflect.DeepEqual(ids, want) {
+			t.Errorf("%T: got descendants %v, %v, want %v", g, ids, err, want)
+		}
+		s := testSecurities()
+		for query, want := range map[string][]string{
+			"entity=0ULT00-E":              {s[0].CUSIP, s[1].CUSIP, s[2].CUSIP},
+			"entity=000XT9-E&issuetype=MU": {s[0].CUSIP},
+			"entity=8C7QCS-E":              {s[0].CUSIP},
+			"entity=0CYC01-E":              {},
+		} {
+			q, _ := url.ParseQuery(query)
+			f, err := ParseFilter(q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			response, err := Search(context.Background(), g, f)
+			if err != nil {
+				t.Fatalf("%s with %T: %s", query, g, err)
+			}
+			got := []string{}
+			for _, sec := range response.Results {
+				got = append(got, sec.CUSIP)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s with %T: got %v, want %v", query, g, got, want)
+			}
+		}
+	}
+}
+
+func TestHierarchyHandler(t *testing.T) {
+	storage, cleanup := testStorage(t)
+	defer cleanup()
+	if err := WriteEntities(storage.(*boltPersistance).db, testEntities(t)); err != nil {
+		t.Fatal(err)
+	}
+	server := Server{Getter: NewSwappable(storage)}
+	get := func(query string) (int, string) {
+		w := httptest.NewRecorder()
+		server.HierarchyHandler(w, httptest.NewRequest("GET", "/hierarchy?"+query, nil))
+		return w.Code, w.Body.String()
+	}
+	code, body := get("key=US8515000006")
+	if code != 200 || !strings.Contains(body, `"Entity":"8C7QCS-E"`) || !strings.Contains(body, `"UltimateParent":"0ULT00-E"`) {
+		t.Errorf("Got %d %s, want the chain to 0ULT00-E", code, body)
+	}
+	if code, body = get("entity=000XT9-E&key=US8515000006"); code != 400 {
+		t.Errorf("Got %d %s for an entity and a key, want 400", code, body)
+	}
+	if code, body = get("key=NOPE00000"); code != 404 {
+		t.Errorf("Got %d %s for an unknown security, want 404", code, body)
+	}
+	registry, err := NewClientRegistry(&Client{
+		Name:        "cusips",
+		Keys:        []string{"secret"},
+		Entitlement: Entitlement{Fields: []string{"Cusip", "LegalEntityId"}, IdentifierTypes: []string{"CUSIP"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entitled := func(query string) (int, string) {
+		r := httptest.NewRequest("GET", "/hierarchy?"+query, nil)
+		r.Header.Set("X-API-Key", "secret")
+		w := httptest.NewRecorder()
+		Guard(registry, nil, server.HierarchyHandler)(w, r)
+		return w.Code, w.Body.String()
+	}
+	if code, body = entitled("key=US8515000006"); code != 403 {
+		t.Errorf("Got %d %s for an ISIN with a CUSIP-only entitlement, want 403", code, body)
+	}
+	if code, body = entitled("key=851500000"); code != 200 {
+		t.Errorf("Got %d %s for a CUSIP with a CUSIP-only entitlement, want 200", code, body)
+	}
+	server.Getter = NewSwappable(nil)
+	if code, body = get("entity=000XT9-E"); code != 503 {
+		t.Errorf("Got %d %s before loading, want 503", code, body)
+	}
+}
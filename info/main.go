@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/nycmonkey/fast_lem"
+	"github.com/pquerna/ffjson/ffjson"
+)
+
+var (
+	dbfile string
+	asJSON bool
+)
+
+func init() {
+	flag.StringVar(&dbfile, "dbfile", "../db/lem.db", "path to the boltdb database to describe")
+	flag.BoolVar(&asJSON, "json", false, "print the metadata as JSON")
+	flag.Parse()
+}
+
+func main() {
+	var db *bolt.DB
+	var err error
+	db, err = bolt.Open(dbfile, 0666, &bolt.Options{Timeout: 1 * time.Second, ReadOnly: true})
+	if err != nil {
+		log.Fatalln("Error opening db:", err)
+	}
+	defer db.Close()
+	var m *fast_lem.Metadata
+	m, err = fast_lem.ReadMetadata(db)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if asJSON {
+		var js []byte
+		js, err = ffjson.Marshal(m)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		os.Stdout.Write(js)
+		fmt.Println()
+		return
+	}
+	fmt.Println("Schema version:", m.SchemaVersion)
+	fmt.Println("Code version:  ", m.CodeVersion)
+	fmt.Println("Started:       ", m.Started.Format(time.RFC3339))
+	fmt.Println("Finished:      ", m.Finished.Format(time.RFC3339))
+	fmt.Println("Duration:      ", m.Finished.Sub(m.Started))
+	fmt.Println("Rows:          ", m.Rows)
+	fmt.Println("Rejects:       ", m.Rejects)
+	if m.Entities > 0 {
+		fmt.Println("Entities:      ", m.Entities)
+	}
+	for _, f := range m.Sources {
+		fmt.Println("Source:        ", f.Name, f.Size, "bytes", "sha256", f.SHA256)
+	}
+	return
+}
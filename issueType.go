@@ -1,5 +1,20 @@
 package fast_lem
 
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pquerna/ffjson/ffjson"
+)
+
+// IssueType is one of the FactSet issue types known when this code was written.  Codes
+// FactSet added since are NA, but are kept verbatim in Description.IssueCode and can be
+// described with RegisterIssueType.
 type IssueType int
 
 const (
@@ -40,154 +55,245 @@ const (
 	WT
 )
 
+// AssetClass groups issue types
+type AssetClass string
+
+const (
+	AssetClassEquity      AssetClass = "Equity"
+	AssetClassFixedIncome AssetClass = "Fixed Income"
+	AssetClassFund        AssetClass = "Fund"
+	AssetClassDerivative  AssetClass = "Derivative"
+	AssetClassCash        AssetClass = "Cash"
+	AssetClassOther       AssetClass = "Other"
+)
+
+// IssueTypeInfo describes an issue type
+type IssueTypeInfo struct {
+	// Code is the FactSet issue type code, such as BD
+	Code       string
+	Label      string
+	AssetClass AssetClass `json:",omitempty"`
+}
+
+// builtinIssueTypes describes each IssueType, indexed by its value
+var builtinIssueTypes = []IssueTypeInfo{
+	NA: {Code: "", Label: "N/A"},
+	AB: {Code: "AB", Label: "Asset-Backed", AssetClass: AssetClassFixedIncome},
+	AD: {Code: "AD", Label: "ADR/GDR", AssetClass: AssetClassEquity},
+	AG: {Code: "AG", Label: "Agency Bond", AssetClass: AssetClassFixedIncome},
+	AI: {Code: "AI", Label: "Alternative Invt", AssetClass: AssetClassFund},
+	BC: {Code: "BC", Label: "Convertible Bond", AssetClass: AssetClassFixedIncome},
+	BD: {Code: "BD", Label: "Bond", AssetClass: AssetClassFixedIncome},
+	CA: {Code: "CA", Label: "Cash/Repo/MM", AssetClass: AssetClassCash},
+	CE: {Code: "CE", Label: "Closed-End Mutual Fund", AssetClass: AssetClassFund},
+	CP: {Code: "CP", Label: "Convertible Preferred", AssetClass: AssetClassEquity},
+	DB: {Code: "DB", Label: "Debenture", AssetClass: AssetClassFixedIncome},
+	DL: {Code: "DL", Label: "Dual Listing", AssetClass: AssetClassEquity},
+	DR: {Code: "DR", Label: "Derivative", AssetClass: AssetClassDerivative},
+	EP: {Code: "EP", Label: "Equity (Pre-IPO)", AssetClass: AssetClassEquity},
+	EQ: {Code: "EQ", Label: "Equity", AssetClass: AssetClassEquity},
+	ET: {Code: "ET", Label: "Exchange Traded Fund", AssetClass: AssetClassFund},
+	FM: {Code: "FM", Label: "First Mortgage", AssetClass: AssetClassFixedIncome},
+	FU: {Code: "FU", Label: "Future Agreement", AssetClass: AssetClassDerivative},
+	FX: {Code: "FX", Label: "Fixed Income/Unclassified", AssetClass: AssetClassFixedIncome},
+	ID: {Code: "ID", Label: "Index", AssetClass: AssetClassOther},
+	LN: {Code: "LN", Label: "Loan", AssetClass: AssetClassFixedIncome},
+	MB: {Code: "MB", Label: "Mortgage-Backed", AssetClass: AssetClassFixedIncome},
+	MT: {Code: "MT", Label: "Medium Term Note", AssetClass: AssetClassFixedIncome},
+	MU: {Code: "MU", Label: "Municipal Bonds", AssetClass: AssetClassFixedIncome},
+	NT: {Code: "NT", Label: "Note", AssetClass: AssetClassFixedIncome},
+	OE: {Code: "OE", Label: "Open-End Mutual Fund", AssetClass: AssetClassFund},
+	OP: {Code: "OP", Label: "Stock Option", AssetClass: AssetClassDerivative},
+	PF: {Code: "PF", Label: "Preferred", AssetClass: AssetClassEquity},
+	PQ: {Code: "PQ", Label: "Private Equity", AssetClass: AssetClassEquity},
+	PV: {Code: "PV", Label: "Private Placement", AssetClass: AssetClassFixedIncome},
+	SH: {Code: "SH", Label: "Short Position", AssetClass: AssetClassOther},
+	UI: {Code: "UI", Label: "Unit Invt Trust", AssetClass: AssetClassFund},
+	UL: {Code: "UL", Label: "Treasury/Long-Term", AssetClass: AssetClassFixedIncome},
+	US: {Code: "US", Label: "Treasury/Short-Term", AssetClass: AssetClassFixedIncome},
+	WT: {Code: "WT", Label: "Warrant/Right", AssetClass: AssetClassDerivative},
+}
+
+// issueTypes describes the built-in issue types and those registered since
+var issueTypes = struct {
+	sync.RWMutex
+	byCode map[string]IssueTypeInfo
+	// byLabel maps lower-cased labels to codes
+	byLabel map[string]string
+	builtin map[string]IssueType
+}{
+	byCode:  make(map[string]IssueTypeInfo),
+	byLabel: make(map[string]string),
+	builtin: make(map[string]IssueType),
+}
+
+func init() {
+	for it, info := range builtinIssueTypes {
+		if it == int(NA) {
+			continue
+		}
+		issueTypes.byCode[info.Code] = info
+		issueTypes.byLabel[strings.ToLower(info.Label)] = info.Code
+		issueTypes.builtin[info.Code] = IssueType(it)
+	}
+}
+
+// IssueTypeFromString returns the IssueType with the given code, or NA if it is not
+// one of the built-in issue types
 func IssueTypeFromString(code string) IssueType {
-	switch code {
-	case "AB":
-		return AB
-	case "AD":
-		return AD
-	case "AG":
-		return AG
-	case "AI":
-		return AI
-	case "BC":
-		return BC
-	case "BD":
-		return BD
-	case "CA":
-		return CA
-	case "CE":
-		return CE
-	case "CP":
-		return CP
-	case "DB":
-		return DB
-	case "DL":
-		return DL
-	case "DR":
-		return DR
-	case "EP":
-		return EP
-	case "EQ":
-		return EQ
-	case "ET":
-		return ET
-	case "FM":
-		return FM
-	case "FU":
-		return FU
-	case "FX":
-		return FX
-	case "ID":
-		return ID
-	case "LN":
-		return LN
-	case "MB":
-		return MB
-	case "MT":
-		return MT
-	case "MU":
-		return MU
-	case "NT":
-		return NT
-	case "OE":
-		return OE
-	case "OP":
-		return OP
-	case "PF":
-		return PF
-	case "PQ":
-		return PQ
-	case "PV":
-		return PV
-	case "SH":
-		return SH
-	case "UI":
-		return UI
-	case "UL":
-		return UL
-	case "US":
-		return US
-	case "WT":
-		return WT
-	default:
-		return NA
+	issueTypes.RLock()
+	defer issueTypes.RUnlock()
+	return issueTypes.builtin[code]
+}
+
+func (it IssueType) info() IssueTypeInfo {
+	if it < 0 || int(it) >= len(builtinIssueTypes) {
+		return IssueTypeInfo{Label: "Unknown Issue Type"}
 	}
+	return builtinIssueTypes[it]
+}
+
+// Code returns the FactSet issue type code, or an empty string for NA
+func (it IssueType) Code() string {
+	return it.info().Code
 }
 
 func (it IssueType) String() string {
-	switch it {
-	case NA:
-		return "N/A"
-	case AB:
-		return "Asset-Backed"
-	case AD:
-		return "ADR/GDR"
-	case AG:
-		return "Agency Bond"
-	case AI:
-		return "Alternative Invt"
-	case BC:
-		return "Convertible Bond"
-	case BD:
-		return "Bond"
-	case CA:
-		return "Cash/Repo/MM"
-	case CE:
-		return "Closed-End Mutual Fund"
-	case CP:
-		return "Convertible Preferred"
-	case DB:
-		return "Debenture"
-	case DL:
-		return "Dual Listing"
-	case DR:
-		return "Derivative"
-	case EP:
-		return "Equity (Pre-IPO)"
-	case EQ:
-		return "Equity"
-	case ET:
-		return "Exchange Traded Fund"
-	case FM:
-		return "First Mortgage"
-	case FU:
-		return "Future Agreement"
-	case FX:
-		return "Fixed Income/Unclassified"
-	case ID:
-		return "Index"
-	case LN:
-		return "Loan"
-	case MB:
-		return "Mortgage-Backed"
-	case MT:
-		return "Medium Term Note"
-	case MU:
-		return "Municipal Bonds"
-	case NT:
-		return "Note"
-	case OE:
-		return "Open-End Mutual Fund"
-	case OP:
-		return "Stock Option"
-	case PF:
-		return "Preferred"
-	case PQ:
-		return "Private Equity"
-	case PV:
-		return "Private Placement"
-	case SH:
-		return "Short Position"
-	case UI:
-		return "Unit Invt Trust"
-	case UL:
-		return "Treasury/Long-Term"
-	case US:
-		return "Treasury/Short-Term"
-	case WT:
-		return "Warrant/Right"
-	default:
-		return "Unknown Issue Type"
+	return it.info().Label
+}
+
+// AssetClass returns the asset class the issue type belongs to, or an empty string
+// for NA
+func (it IssueType) AssetClass() AssetClass {
+	return it.info().AssetClass
+}
+
+// LookupIssueType describes the built-in or registered issue type with code
+func LookupIssueType(code string) (IssueTypeInfo, bool) {
+	issueTypes.RLock()
+	defer issueTypes.RUnlock()
+	info, ok := issueTypes.byCode[code]
+	return info, ok
+}
+
+// lookupIssueTypeLabel describes the built-in or registered issue type with label,
+// ignoring case
+func lookupIssueTypeLabel(label string) (IssueTypeInfo, bool) {
+	issueTypes.RLock()
+	defer issueTypes.RUnlock()
+	code, ok := issueTypes.byLabel[strings.ToLower(label)]
+	if !ok {
+		return IssueTypeInfo{}, false
+	}
+	return issueTypes.byCode[code], true
+}
+
+// RegisterIssueType describes an issue type FactSet added after this code was
+// written.  Built-in codes cannot be redefined, and labels must be unique.
+func RegisterIssueType(info IssueTypeInfo) error {
+	if len(info.Code) == 0 || len(info.Label) == 0 {
+		return fmt.Errorf("issue type %+v needs a code and a label", info)
+	}
+	issueTypes.Lock()
+	defer issueTypes.Unlock()
+	if _, ok := issueTypes.builtin[info.Code]; ok {
+		return fmt.Errorf("issue type %s is built in", info.Code)
+	}
+	label := strings.ToLower(info.Label)
+	if code, ok := issueTypes.byLabel[label]; ok && code != info.Code {
+		return fmt.Errorf("issue types %s and %s share the label %q", code, info.Code, info.Label)
+	}
+	if old, ok := issueTypes.byCode[info.Code]; ok {
+		delete(issueTypes.byLabel, strings.ToLower(old.Label))
+	}
+	issueTypes.byCode[info.Code] = info
+	issueTypes.byLabel[label] = info.Code
+	return nil
+}
+
+// LoadIssueTypes registers each issue type in a JSON array of IssueTypeInfo at path
+func LoadIssueTypes(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var infos []IssueTypeInfo
+	err = json.Unmarshal(data, &infos)
+	if err != nil {
+		return fmt.Errorf("parse %s: %s", path, err)
+	}
+	for _, info := range infos {
+		if err = RegisterIssueType(info); err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+	}
+	return nil
+}
+
+// IssueTypes returns every built-in and registered issue type, sorted by code
+func IssueTypes() []IssueTypeInfo {
+	issueTypes.RLock()
+	infos := make([]IssueTypeInfo, 0, len(issueTypes.byCode))
+	for _, info := range issueTypes.byCode {
+		infos = append(infos, info)
+	}
+	issueTypes.RUnlock()
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Code < infos[j].Code })
+	return infos
+}
+
+// IssueTypesHandler responds with the taxonomy of issue types, limited to one asset
+// class by the assetclass query parameter
+func IssueTypesHandler(w http.ResponseWriter, r *http.Request) {
+	infos := IssueTypes()
+	if class := r.URL.Query().Get("assetclass"); len(class) > 0 {
+		selected := []IssueTypeInfo{}
+		for _, info := range infos {
+			if strings.EqualFold(string(info.AssetClass), class) {
+				selected = append(selected, info)
+			}
+		}
+		infos = selected
+	}
+	js, err := json.Marshal(infos)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
+
+// ParseIssueType returns the IssueType with the given FactSet code, such as BD, or
+// label, such as Bond.  Codes and labels are matched ignoring case; an empty string
+// and N/A are NA.
+func ParseIssueType(s string) (IssueType, error) {
+	if len(s) == 0 || strings.EqualFold(s, NA.String()) {
+		return NA, nil
+	}
+	if it := IssueTypeFromString(strings.ToUpper(s)); it != NA {
+		return it, nil
+	}
+	for it := AB; it <= WT; it++ {
+		if strings.EqualFold(s, it.String()) {
+			return it, nil
+		}
+	}
+	return NA, fmt.Errorf("unknown issue type %q", s)
+}
+
+// MarshalJSON encodes it as its code
+func (it IssueType) MarshalJSON() ([]byte, error) {
+	return ffjson.Marshal(it.Code())
+}
+
+// UnmarshalJSON decodes an issue type from its code or label
+func (it *IssueType) UnmarshalJSON(data []byte) error {
+	var s string
+	err := ffjson.Unmarshal(data, &s)
+	if err != nil {
+		return err
 	}
+	*it, err = ParseIssueType(s)
+	return err
 }
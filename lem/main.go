@@ -1,66 +1,357 @@
 package main
 
 import (
-	"errors"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/boltdb/bolt"
 	"github.com/nycmonkey/fast_lem"
+	"github.com/nycmonkey/fast_lem/bond"
 )
 
 var (
-	source  string
-	dbfile  string
-	port    int
-	storage fast_lem.Getter
+	dbfile     string
+	snapshot   string
+	backend    string
+	port       int
+	checks     string
+	issueTypes string
+	timeout    time.Duration
+	cacheSize  int
+	accessLog  string
+	metrics    = fast_lem.NewMetrics()
+
+	readTimeout       time.Duration
+	readHeaderTimeout time.Duration
+	writeTimeout      time.Duration
+	idleTimeout       time.Duration
+	shutdownTimeout   time.Duration
+	maxBody           int64
+	maxKeys           int
+	tlsCert           string
+	tlsKey            string
+	clients           string
+	clientCA          string
+	auditLog          string
+	cursorKey         string
 )
 
 func init() {
 	flag.IntVar(&port, "port", 8888, "port on which the server will listen")
 	flag.StringVar(&dbfile, "dbfile", "../db/lem.db",
 		"path to a boltdb database where the data will be stored")
+	flag.StringVar(&snapshot, "snapshot", "../db/lem.snapshot",
+		"path to a security master snapshot, used by the snapshot and mapped backends")
+	flag.StringVar(&backend, "backend", "bolt",
+		"where lookups are served from: bolt (the database file), memory (the database "+
+			"loaded into memory), snapshot (a gob snapshot loaded into memory) or mapped "+
+			"(a mapped snapshot file)")
+	flag.DurationVar(&timeout, "lookup-timeout", 0,
+		"maximum time spent resolving the keys of one query; 0 for no limit")
+	flag.IntVar(&cacheSize, "cache-size", 0,
+		"number of looked-up securities to keep in memory; 0 disables the cache")
+	flag.StringVar(&accessLog, "access-log", "-",
+		"path to which a JSON line is appended for each request; - for standard output, "+
+			"or empty to disable")
+	flag.StringVar(&issueTypes, "issuetypes", "",
+		"path to a JSON array of issue types FactSet added since this server was built, "+
+			"each with a Code, Label and AssetClass")
+	flag.StringVar(&checks, "checks", "",
+		"path to a JSON data-quality check suite, such as etl/checks.example.json; the built-in "+
+			"suite, which only requires some rows, is used if empty")
+	flag.DurationVar(&readTimeout, "read-timeout", 30*time.Second,
+		"maximum time to read a request, including its body; 0 for no limit")
+	flag.DurationVar(&readHeaderTimeout, "read-header-timeout", 10*time.Second,
+		"maximum time to read a request's headers; 0 for no limit")
+	flag.DurationVar(&writeTimeout, "write-timeout", 60*time.Second,
+		"maximum time from the end of a request's headers to the end of its response; 0 for no limit")
+	flag.DurationVar(&idleTimeout, "idle-timeout", 120*time.Second,
+		"maximum time to keep an idle keep-alive connection open; 0 for no limit")
+	flag.DurationVar(&shutdownTimeout, "shutdown-timeout", 30*time.Second,
+		"maximum time to wait for requests in flight to finish after SIGTERM")
+	flag.Int64Var(&maxBody, "max-body", 8<<20,
+		"maximum size in bytes of a query body; 0 for no limit")
+	flag.IntVar(&maxKeys, "max-keys", 100000,
+		"maximum number of keys in one query; 0 for no limit")
+	flag.StringVar(&tlsCert, "tls-cert", "",
+		"path to a PEM certificate chain; with -tls-key, serve HTTPS instead of HTTP")
+	flag.StringVar(&tlsKey, "tls-key", "",
+		"path to the PEM private key of the -tls-cert certificate")
+	flag.StringVar(&clients, "clients", "",
+		"path to a JSON registry of the clients allowed to query, their API keys and quotas; "+
+			"if empty, queries are not authenticated")
+	flag.StringVar(&clientCA, "client-ca", "",
+		"path to PEM CA certificates; with HTTPS, clients may authenticate with certificates they sign")
+	flag.StringVar(&auditLog, "audit-log", "",
+		"path to which a JSON line is appended recording each authenticated query; - for "+
+			"standard output, or empty to disable")
+	flag.StringVar(&cursorKey, "cursor-key", os.Getenv("LEM_CURSOR_KEY"),
+		"secret that encrypts the search page tokens of clients not entitled to CUSIPs, shared by "+
+			"servers behind one load balancer; defaults to $LEM_CURSOR_KEY, or a random key if empty")
 	flag.Parse()
 }
 
-func checkKnownValue() (string, error) {
-	var ISIN = `US00037NMH60`
-	var EntityID = `06L3Q8-E`
-	response, err := storage.Get(ISIN)
+// loadChecks returns the configured data-quality suite
+func loadChecks() (*fast_lem.CheckSuite, error) {
+	if len(checks) > 0 {
+		return fast_lem.LoadCheckSuite(checks)
+	}
+	return fast_lem.DefaultChecks, nil
+}
+
+// openStorage returns the Getter selected by the backend flag, and what must be
+// closed once it is no longer used, if anything
+func openStorage() (g fast_lem.Getter, closer io.Closer, err error) {
+	switch backend {
+	case "snapshot":
+		var m *fast_lem.SecurityMaster
+		m, err = fast_lem.LoadSnapshot(snapshot)
+		return m, nil, err
+	case "mapped":
+		var m *fast_lem.MappedMaster
+		m, err = fast_lem.OpenMappedMaster(snapshot)
+		return m, m, err
+	}
+	db, err := bolt.Open(dbfile, 0666, &bolt.Options{Timeout: 1 * time.Second, ReadOnly: true})
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error opening db: %s", err)
+	}
+	switch backend {
+	case "bolt":
+		return fast_lem.NewInstrumentedGetter(db, metrics), db, nil
+	case "memory":
+		defer db.Close()
+		g, err = fast_lem.NewSecurityMasterFromBolt(db)
+		return g, nil, err
+	}
+	db.Close()
+	return nil, nil, fmt.Errorf("unknown backend %q", backend)
+}
+
+// load opens the storage and runs the data-quality checks against it
+func load(suite *fast_lem.CheckSuite) (g fast_lem.Getter, closer io.Closer, err error) {
+	start := time.Now()
+	g, closer, err = openStorage()
+	if err != nil {
+		return
+	}
+	fmt.Println("Opened", backend, "backend in", time.Now().Sub(start))
+	report := suite.Check(g)
+	fmt.Print(report)
+	err = report.Error()
+	if err != nil && closer != nil {
+		closer.Close()
+	}
+	return
+}
+
+// handleSignals swaps in freshly loaded storage whenever the process receives SIGHUP,
+// for example after etl has published a new database.  On SIGTERM or SIGINT it stops
+// srv, waits for requests in flight to finish and closes the storage, then closes done.
+func handleSignals(srv *http.Server, storage *fast_lem.Swappable, health *fast_lem.Health, suite *fast_lem.CheckSuite, closer io.Closer, done chan<- struct{}) {
+	defer close(done)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	term := make(chan os.Signal, 1)
+	signal.Notify(term, syscall.SIGTERM, os.Interrupt)
+	for {
+		select {
+		case <-hup:
+			log.Println("Reloading", backend, "backend")
+			g, newCloser, err := load(suite)
+			if err != nil {
+				health.LoadFailed(err)
+				log.Println("Reload failed; still serving the previous data:", err)
+				continue
+			}
+			storage.Swap(g)
+			health.Loaded()
+			if closer != nil {
+				closer.Close()
+			}
+			closer = newCloser
+			log.Println("Reload complete")
+		case sig := <-term:
+			log.Printf("Received %s; draining requests in flight", sig)
+			ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			err := srv.Shutdown(ctx)
+			cancel()
+			if err != nil {
+				log.Println("Shutdown incomplete:", err)
+			}
+			if closer != nil {
+				if err = closer.Close(); err != nil {
+					log.Println("Error closing", backend, "backend:", err)
+				}
+			}
+			log.Println("Shutdown complete")
+			return
+		}
+	}
+}
+
+// openLog returns the file a log flag names: nil if it is empty, or standard output
+// for -
+func openLog(path string) (io.Writer, error) {
+	switch path {
+	case "":
+		return nil, nil
+	case "-":
+		return os.Stdout, nil
+	}
+	return os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+}
+
+// guard wraps h with authentication if a client registry is configured
+func guard(auth fast_lem.Authenticator, audit *fast_lem.AuditLog, h http.HandlerFunc) http.HandlerFunc {
+	if auth == nil {
+		return h
+	}
+	return fast_lem.Guard(auth, audit, h)
+}
+
+// tlsConfig returns the TLS configuration for verifying client certificates, if any
+func tlsConfig() (*tls.Config, error) {
+	if len(clientCA) == 0 {
+		return nil, nil
+	}
+	pem, err := ioutil.ReadFile(clientCA)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	if len(response) < 1 {
-		return "", errors.New("Response was empty")
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", clientCA)
 	}
-	security := response[0]
-	if security.LegalEntityID != EntityID {
-		return "", errors.New("Unexpected entity ID: " + security.LegalEntityID)
+	return &tls.Config{ClientCAs: pool, ClientAuth: tls.VerifyClientCertIfGiven}, nil
+}
+
+// dataFileSize returns the size of the file the backend serves from
+func dataFileSize() float64 {
+	path := dbfile
+	if backend == "snapshot" || backend == "mapped" {
+		path = snapshot
 	}
-	return `Test OK: ` + ISIN + ` => ` + fmt.Sprintf("%+v", security), nil
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return float64(fi.Size())
 }
 
 func main() {
-	var db *bolt.DB
-	var err error
-	db, err = bolt.Open(dbfile, 0666, &bolt.Options{Timeout: 1 * time.Second, ReadOnly: true})
+	if (len(tlsCert) > 0) != (len(tlsKey) > 0) {
+		log.Fatalln("-tls-cert and -tls-key must be given together")
+	}
+	if len(clientCA) > 0 && len(tlsCert) == 0 {
+		log.Fatalln("-client-ca requires -tls-cert and -tls-key")
+	}
+	var al *fast_lem.AccessLog
+	var audit *fast_lem.AuditLog
+	if w, err := openLog(accessLog); err != nil {
+		log.Fatalln("Error opening access log:", err)
+	} else if w != nil {
+		al = fast_lem.NewAccessLog(w)
+	}
+	if w, err := openLog(auditLog); err != nil {
+		log.Fatalln("Error opening audit log:", err)
+	} else if w != nil {
+		audit = fast_lem.NewAuditLog(w)
+	}
+	var auth fast_lem.Authenticator
+	if len(clients) > 0 {
+		registry, err := fast_lem.LoadClientRegistry(clients)
+		if err != nil {
+			log.Fatalln("Error loading clients:", err)
+		}
+		auth = registry
+	} else {
+		log.Println("No -clients registry given; queries are not authenticated")
+	}
+	tlsConf, err := tlsConfig()
+	if err != nil {
+		log.Fatalln("Error loading client CA:", err)
+	}
+	if len(issueTypes) > 0 {
+		if err = fast_lem.LoadIssueTypes(issueTypes); err != nil {
+			log.Fatalln("Error loading issue types:", err)
+		}
+	}
+	suite, err := loadChecks()
 	if err != nil {
-		log.Fatalln("Error opening db:", err)
+		log.Fatalln("Error loading checks:", err)
 	}
-	defer db.Close()
-	storage = fast_lem.NewGetter(db)
-	sanityCheck, err := checkKnownValue()
+	storage := fast_lem.NewSwappable(nil)
+	health := fast_lem.NewHealth(storage, suite)
+	g, closer, err := load(suite)
 	if err != nil {
-		log.Fatalln(err)
-	}
-	fmt.Println(sanityCheck)
-	server := fast_lem.Server{storage}
-	http.HandleFunc("/query", server.QueryHandler)
-	listen := fmt.Sprintf(":%d", port)
-	fmt.Println("Listening on", listen)
-	log.Fatal(http.ListenAndServe(listen, nil))
+		// stay up but unready, so that a fixed database can be loaded with SIGHUP
+		health.LoadFailed(err)
+		log.Println("Not ready to serve:", err)
+	} else {
+		storage.Swap(g)
+		health.Loaded()
+	}
+	metrics.GaugeFunc("lem_db_size_bytes", "Size of the database or snapshot file being served.", dataFileSize)
+	server := fast_lem.Server{
+		Getter:        storage,
+		LookupTimeout: timeout,
+		MaxBodyBytes:  maxBody,
+		MaxKeys:       maxKeys,
+		Metrics:       metrics,
+		Health:        health,
+		CursorKey:     []byte(cursorKey),
+	}
+	mux := http.NewServeMux()
+	if cacheSize > 0 {
+		cache := fast_lem.NewCache(storage, cacheSize)
+		storage.OnSwap(cache.Purge)
+		cache.RegisterMetrics(metrics)
+		server.Getter = cache
+		mux.HandleFunc("/cache", fast_lem.Instrument("cache", guard(auth, audit, cache.StatsHandler), metrics, al))
+	}
+	mux.HandleFunc("/query", fast_lem.Instrument("query", guard(auth, audit, server.QueryHandler), metrics, al))
+	analytics := bond.Server{Getter: server.Getter}
+	mux.HandleFunc("/analytics", fast_lem.Instrument("analytics", guard(auth, audit, analytics.AnalyticsHandler), metrics, al))
+	mux.HandleFunc("/search", fast_lem.Instrument("search", guard(auth, audit, server.SearchHandler), metrics, al))
+	mux.HandleFunc("/hierarchy", fast_lem.Instrument("hierarchy", guard(auth, audit, server.HierarchyHandler), metrics, al))
+	mux.HandleFunc("/info", fast_lem.Instrument("info", guard(auth, audit, server.InfoHandler), metrics, al))
+	mux.HandleFunc("/issuetypes", fast_lem.Instrument("issuetypes", fast_lem.IssueTypesHandler, metrics, al))
+	mux.HandleFunc("/metrics", metrics.Handler)
+	mux.HandleFunc("/healthz", health.HealthzHandler)
+	mux.HandleFunc("/readyz", fast_lem.Instrument("readyz", health.ReadyzHandler, metrics, nil))
+	srv := &http.Server{
+		Addr:              fmt.Sprintf(":%d", port),
+		Handler:           mux,
+		ReadTimeout:       readTimeout,
+		ReadHeaderTimeout: readHeaderTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+		TLSConfig:         tlsConf,
+	}
+	done := make(chan struct{})
+	go handleSignals(srv, storage, health, suite, closer, done)
+	if len(tlsCert) > 0 {
+		fmt.Println("Listening for HTTPS on", srv.Addr)
+		err = srv.ListenAndServeTLS(tlsCert, tlsKey)
+	} else {
+		fmt.Println("Listening on", srv.Addr)
+		err = srv.ListenAndServe()
+	}
+	if err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+	<-done
 	return
 }
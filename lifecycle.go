@@ -0,0 +1,79 @@
+package fast_lem
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Lifecycle is the trading status of a Security as of a date
+type Lifecycle int
+
+const (
+	// LifecycleUnknown means the status was not computed
+	LifecycleUnknown Lifecycle = iota
+	LifecyclePending
+	LifecycleActive
+	LifecycleTerminated
+)
+
+func (l Lifecycle) String() string {
+	switch l {
+	case LifecyclePending:
+		return "pending"
+	case LifecycleActive:
+		return "active"
+	case LifecycleTerminated:
+		return "terminated"
+	}
+	return ""
+}
+
+// MarshalJSON encodes l as its name
+func (l Lifecycle) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(l.String())), nil
+}
+
+// UnmarshalJSON decodes a Lifecycle from its name
+func (l *Lifecycle) UnmarshalJSON(data []byte) error {
+	name, err := strconv.Unquote(string(data))
+	if err != nil {
+		return fmt.Errorf("lifecycle status %s: %s", data, err)
+	}
+	for _, candidate := range []Lifecycle{LifecycleUnknown, LifecyclePending, LifecycleActive, LifecycleTerminated} {
+		if candidate.String() == name {
+			*l = candidate
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown lifecycle status %q", name)
+}
+
+// ParseAsOf parses a date in FactSetDateFormat, returning the current time if it is empty
+func ParseAsOf(date string) (time.Time, error) {
+	if len(date) == 0 {
+		return time.Now(), nil
+	}
+	t, err := time.Parse(FactSetDateFormat, date)
+	if err != nil {
+		return t, fmt.Errorf("invalid date %q; expected YYYY-MM-DD", date)
+	}
+	return t, nil
+}
+
+// withLifecycle sets the Status of each Security found as of asOf, replacing those
+// terminated by then with a Terminated result if exclude is set.  The Securities are
+// copied, so those held by a cache or in-memory master are left alone.
+func withLifecycle(results []Result, asOf time.Time, exclude bool) {
+	for i, r := range results {
+		if r.Security == nil {
+			continue
+		}
+		s := r.Security.AsOf(asOf)
+		if exclude && s.Status == LifecycleTerminated {
+			results[i] = Result{Key: r.Key, Type: r.Type, Status: Terminated}
+			continue
+		}
+		results[i].Security = s
+	}
+}
@@ -0,0 +1,60 @@
+package fast_lem
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLifecycle(t *testing.T) {
+	s := New("851500000", "", "", "", "", "MU", "5", "2007-07-01")
+	if err := s.SetLifecycleDates("2002-12-15", ""); err != nil {
+		t.Fatal(err)
+	}
+	for date, want := range map[string]Lifecycle{
+		"2002-12-14": LifecyclePending,
+		"2002-12-15": LifecycleActive,
+		"2007-07-01": LifecycleActive,
+		"2007-07-02": LifecycleTerminated,
+	} {
+		asOf, _ := time.Parse(FactSetDateFormat, date)
+		if got := s.Lifecycle(asOf); got != want {
+			t.Errorf("%s: got %s, want %s", date, got, want)
+		}
+	}
+	s.Termination = "2005-01-31"
+	if got := s.Lifecycle(time.Date(2005, 1, 31, 12, 0, 0, 0, time.UTC)); got != LifecycleTerminated {
+		t.Errorf("Got %s on the termination date, want terminated", got)
+	}
+	if err := s.SetLifecycleDates("2002/12/15", ""); err == nil {
+		t.Error("Accepted a malformed inception date")
+	}
+}
+
+func TestQueryHandlerExcludesTerminated(t *testing.T) {
+	storage, cleanup := testStorage(t)
+	defer cleanup()
+	server := Server{Getter: storage}
+	s := testSecurities()
+	query := func(body string) (string, string) {
+		w := httptest.NewRecorder()
+		server.QueryHandler(w, httptest.NewRequest("POST", "/query", strings.NewReader(body)))
+		return w.Body.String(), w.Header().Get("X-Terminated-Keys")
+	}
+	body, terminated := query(`{"Keys":["` + s[0].CUSIP + `","` + s[2].CUSIP + `"],"AsOf":"2008-01-01"}`)
+	if !strings.Contains(body, `"Status":"terminated"`) || !strings.Contains(body, `"Status":"active"`) || len(terminated) > 0 {
+		t.Errorf("Got %s, %q, want one terminated and one active security", body, terminated)
+	}
+	body, terminated = query(`{"Keys":["` + s[0].CUSIP + `","` + s[2].CUSIP + `"],"AsOf":"2008-01-01","ExcludeTerminated":true}`)
+	if strings.Contains(body, s[0].CUSIP) || !strings.Contains(body, s[2].CUSIP) || terminated != "1" {
+		t.Errorf("Got %s, %q, want the terminated security excluded", body, terminated)
+	}
+	body, _ = query(`{"Keys":["` + s[0].CUSIP + `"],"AsOf":"2008-01-01","ExcludeTerminated":true,"WithStatus":true}`)
+	if !strings.Contains(body, `"Status":"terminated"}`) {
+		t.Errorf("Got %s, want the key reported as terminated", body)
+	}
+	if body, _ = query(`{"Keys":["` + s[0].CUSIP + `"],"AsOf":"2008"}`); !strings.Contains(body, "invalid date") {
+		t.Errorf("Got %s, want a bad date rejected", body)
+	}
+}
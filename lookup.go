@@ -0,0 +1,225 @@
+package fast_lem
+
+import (
+	"context"
+	"errors"
+)
+
+// IdentifierType classifies a lookup key by the index it is resolved against
+type IdentifierType int
+
+const (
+	InvalidIdentifier IdentifierType = iota
+	CUSIPIdentifier
+	ISINIdentifier
+	SEDOLIdentifier
+)
+
+// IdentifierTypeOf classifies key by length: 12 characters for an ISIN, 7 for a SEDOL
+// and anything else up to 12 for a CUSIP.  Empty keys, longer keys and keys containing
+// spaces or control characters are invalid.
+func IdentifierTypeOf(key string) IdentifierType {
+	if len(key) == 0 || len(key) > 12 {
+		return InvalidIdentifier
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] >= 0x7f {
+			return InvalidIdentifier
+		}
+	}
+	switch len(key) {
+	case 12:
+		return ISINIdentifier
+	case 7:
+		return SEDOLIdentifier
+	default:
+		return CUSIPIdentifier
+	}
+}
+
+func (it IdentifierType) String() string {
+	switch it {
+	case CUSIPIdentifier:
+		return "CUSIP"
+	case ISINIdentifier:
+		return "ISIN"
+	case SEDOLIdentifier:
+		return "SEDOL"
+	default:
+		return "invalid"
+	}
+}
+
+// Status is the outcome of looking up one key
+type Status int
+
+const (
+	Found Status = iota
+	NotFound
+	Invalid
+	Failed
+	// Denied keys are of a type the client is not entitled to look up
+	Denied
+	// Terminated keys identify securities terminated as of the query date, excluded
+	// at the client's request
+	Terminated
+)
+
+func (st Status) String() string {
+	switch st {
+	case Found:
+		return "found"
+	case NotFound:
+		return "not_found"
+	case Invalid:
+		return "invalid"
+	case Denied:
+		return "denied"
+	case Terminated:
+		return "terminated"
+	default:
+		return "error"
+	}
+}
+
+// Result is the outcome of looking up one key of a batch
+type Result struct {
+	Key    string
+	Type   IdentifierType
+	Status Status
+	// Security is nil unless Status is Found
+	Security *Security
+	// Err is set when Status is Failed
+	Err error
+}
+
+// Looker resolves each key of a batch independently, so that one bad key does not
+// fail the others.  The error is reserved for the batch as a whole, such as ctx
+// being done.
+type Looker interface {
+	Lookup(ctx context.Context, keys ...string) ([]Result, error)
+}
+
+// Lookup resolves keys with g, using its Lookup method if it is a Looker.  Otherwise
+// the batch is tried whole and, if it fails, key by key.
+func Lookup(ctx context.Context, g Getter, keys ...string) ([]Result, error) {
+	if l, ok := g.(Looker); ok {
+		return l.Lookup(ctx, keys...)
+	}
+	response, err := GetContext(ctx, g, keys...)
+	if err == nil {
+		results := make([]Result, len(keys))
+		for i, k := range keys {
+			results[i] = newResult(k, IdentifierTypeOf(k), response[i], nil)
+		}
+		return results, nil
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return lookupEach(ctx, keys, func(key string) (*Security, error) {
+		response, err := GetContext(ctx, g, key)
+		if err != nil {
+			return nil, err
+		}
+		return response[0], nil
+	})
+}
+
+// lookupEach resolves every valid key with get, checking ctx between keys
+func lookupEach(ctx context.Context, keys []string, get func(key string) (*Security, error)) ([]Result, error) {
+	results := make([]Result, len(keys))
+	for i, k := range keys {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+		t := IdentifierTypeOf(k)
+		if t == InvalidIdentifier {
+			results[i] = Result{Key: k, Type: t, Status: Invalid}
+			continue
+		}
+		s, err := get(k)
+		results[i] = newResult(k, t, s, err)
+	}
+	return results, nil
+}
+
+func newResult(key string, t IdentifierType, s *Security, err error) Result {
+	r := Result{Key: key, Type: t}
+	switch {
+	case t == InvalidIdentifier:
+		r.Status = Invalid
+	case err != nil:
+		r.Status = Failed
+		r.Err = err
+	case s == nil || len(s.CUSIP) == 0:
+		r.Status = NotFound
+	default:
+		r.Status = Found
+		r.Security = s
+	}
+	return r
+}
+
+// KeyResult returns the JSON representation of r
+func (r Result) KeyResult() *KeyResult {
+	kr := &KeyResult{
+		Key:      r.Key,
+		Status:   r.Status.String(),
+		Security: r.Security,
+	}
+	if r.Type != InvalidIdentifier {
+		kr.Type = r.Type.String()
+	}
+	if r.Err != nil {
+		kr.Error = r.Err.Error()
+	}
+	return kr
+}
+
+// Result converts kr back into the Result it reports
+func (kr *KeyResult) Result() Result {
+	r := Result{Key: kr.Key, Security: kr.Security}
+	switch kr.Type {
+	case CUSIPIdentifier.String():
+		r.Type = CUSIPIdentifier
+	case ISINIdentifier.String():
+		r.Type = ISINIdentifier
+	case SEDOLIdentifier.String():
+		r.Type = SEDOLIdentifier
+	}
+	switch kr.Status {
+	case Found.String():
+		r.Status = Found
+	case NotFound.String():
+		r.Status = NotFound
+	case Invalid.String():
+		r.Status = Invalid
+	case Denied.String():
+		r.Status = Denied
+	case Terminated.String():
+		r.Status = Terminated
+	default:
+		r.Status = Failed
+		r.Err = errors.New(kr.Error)
+	}
+	return r
+}
+
+// Securities returns the Security of each result, with an empty Security for keys that
+// were not found, or the error of the first result that failed
+func Securities(results []Result) ([]*Security, error) {
+	response := make([]*Security, len(results))
+	for i, r := range results {
+		if r.Status == Failed {
+			return nil, r.Err
+		}
+		response[i] = r.Security
+		if response[i] == nil {
+			response[i] = &Security{}
+		}
+	}
+	return response, nil
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/nycmonkey/fast_lem"
+	"github.com/nycmonkey/fast_lem/client"
+	"github.com/pquerna/ffjson/ffjson"
+)
+
+var (
+	dbfile    string
+	server    string
+	apiKey    string
+	format    string
+	fields    []string
+	batchSize int
+)
+
+func init() {
+	var fieldList, issueTypeConfig string
+	flag.StringVar(&dbfile, "dbfile", "../db/lem.db", "path to the boltdb database to search, opened read-only")
+	flag.StringVar(&server, "server", "", "URL of a lem server to query instead of -dbfile, e.g. http://localhost:8888")
+	flag.StringVar(&apiKey, "api-key", os.Getenv("LEM_API_KEY"), "API key sent to -server; defaults to $LEM_API_KEY")
+	flag.StringVar(&format, "format", "table", "output format: table, json or csv")
+	flag.StringVar(&fieldList, "fields", "Cusip,ISIN,Sedol,Ticker,LegalEntityId,Status,Description",
+		"comma-separated fields to print for each security found; any of "+strings.Join(fast_lem.SecurityFields, ","))
+	flag.StringVar(&issueTypeConfig, "issuetypes", "",
+		"path to a JSON array of issue types FactSet added since this tool was built")
+	flag.IntVar(&batchSize, "batch", 1000, "number of identifiers read from standard input per lookup")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: lookup [flags] [identifier ...]")
+		fmt.Fprintln(os.Stderr, "Looks up CUSIPs, ISINs and SEDOLs given as arguments or, if there are none or the only")
+		fmt.Fprintln(os.Stderr, "argument is -, one per line on standard input.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if len(issueTypeConfig) > 0 {
+		if err := fast_lem.LoadIssueTypes(issueTypeConfig); err != nil {
+			log.Fatalln("Error loading issue types:", err)
+		}
+	}
+	fields = strings.Split(fieldList, ",")
+	for _, f := range fields {
+		if _, ok := (&fast_lem.Security{}).Field(f); !ok {
+			log.Fatalf("Unknown field %q; expected one of %s", f, strings.Join(fast_lem.SecurityFields, ","))
+		}
+	}
+}
+
+// printer writes lookup results in the selected format
+type printer interface {
+	print(results []fast_lem.Result) error
+	flush() error
+}
+
+func newPrinter(w io.Writer) (printer, error) {
+	switch format {
+	case "table":
+		return &tablePrinter{w: tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)}, nil
+	case "json":
+		return &jsonPrinter{w: w}, nil
+	case "csv":
+		return &csvPrinter{w: csv.NewWriter(w)}, nil
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// columns returns the row printed for r: the key, the index that matched it, the
+// outcome and the requested fields
+func columns(r fast_lem.Result) []string {
+	row := []string{r.Key, r.Type.String(), r.Status.String()}
+	if r.Status == fast_lem.Failed {
+		row[2] += ": " + r.Err.Error()
+	}
+	for _, f := range fields {
+		var v string
+		if r.Security != nil {
+			v, _ = r.Security.Field(f)
+		}
+		row = append(row, v)
+	}
+	return row
+}
+
+func header() []string {
+	return append([]string{"Key", "Index", "Result"}, fields...)
+}
+
+type tablePrinter struct {
+	w       *tabwriter.Writer
+	started bool
+}
+
+func (tp *tablePrinter) print(results []fast_lem.Result) error {
+	if !tp.started {
+		fmt.Fprintln(tp.w, strings.Join(header(), "\t"))
+		tp.started = true
+	}
+	for _, r := range results {
+		fmt.Fprintln(tp.w, strings.Join(columns(r), "\t"))
+	}
+	return tp.w.Flush()
+}
+
+func (tp *tablePrinter) flush() error { return tp.w.Flush() }
+
+type jsonPrinter struct {
+	w io.Writer
+}
+
+func (jp *jsonPrinter) print(results []fast_lem.Result) error {
+	for _, r := range results {
+		js, err := ffjson.Marshal(r.KeyResult())
+		if err != nil {
+			return err
+		}
+		js = append(js, '\n')
+		if _, err = jp.w.Write(js); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (jp *jsonPrinter) flush() error { return nil }
+
+type csvPrinter struct {
+	w       *csv.Writer
+	started bool
+}
+
+func (cp *csvPrinter) print(results []fast_lem.Result) error {
+	if !cp.started {
+		cp.w.Write(header())
+		cp.started = true
+	}
+	for _, r := range results {
+		cp.w.Write(columns(r))
+	}
+	cp.w.Flush()
+	return cp.w.Error()
+}
+
+func (cp *csvPrinter) flush() error {
+	cp.w.Flush()
+	return cp.w.Error()
+}
+
+// openGetter returns the server client or the read-only database selected by the flags
+func openGetter() (fast_lem.Getter, func(), error) {
+	if len(server) > 0 {
+		c := client.New(server)
+		c.APIKey = apiKey
+		return c, func() {}, nil
+	}
+	if _, err := os.Stat(dbfile); err != nil {
+		return nil, nil, err
+	}
+	db, err := bolt.Open(dbfile, 0666, &bolt.Options{Timeout: 1 * time.Second, ReadOnly: true})
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error opening db: %s", err)
+	}
+	return fast_lem.NewGetter(db), func() { db.Close() }, nil
+}
+
+// interactive reports whether standard input is a terminal
+func interactive() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+func main() {
+	g, closeGetter, err := openGetter()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer closeGetter()
+	p, err := newPrinter(os.Stdout)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	lookup := func(keys []string) {
+		results, err := fast_lem.Lookup(context.Background(), g, keys...)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		now := time.Now()
+		for i, r := range results {
+			// a server computes the status itself; a database leaves it to us
+			if r.Security != nil && r.Security.Status == fast_lem.LifecycleUnknown {
+				results[i].Security = r.Security.AsOf(now)
+			}
+		}
+		if err = p.print(results); err != nil {
+			log.Fatalln(err)
+		}
+	}
+	args := flag.Args()
+	if len(args) > 0 && !(len(args) == 1 && args[0] == "-") {
+		lookup(args)
+		return
+	}
+	prompt := interactive()
+	if prompt {
+		fmt.Fprint(os.Stderr, "> ")
+	}
+	var keys []string
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		key := strings.TrimSpace(scanner.Text())
+		if len(key) > 0 {
+			keys = append(keys, key)
+		}
+		if len(keys) > 0 && (prompt || len(keys) >= batchSize) {
+			lookup(keys)
+			keys = keys[:0]
+		}
+		if prompt {
+			fmt.Fprint(os.Stderr, "> ")
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		log.Fatalln(err)
+	}
+	if len(keys) > 0 {
+		lookup(keys)
+	}
+	if err = p.flush(); err != nil {
+		log.Fatalln(err)
+	}
+}
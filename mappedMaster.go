@@ -0,0 +1,503 @@
+package fast_lem
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+// The mapped snapshot format lays a security master out so that it can be memory
+// mapped and searched in place.  All integers are little-endian.
+//
+//	header     mappedHeaderSize bytes, see mappedHeader
+//	records    Count fixed-width records sorted by CUSIP, see the rec* offsets
+//	isin index ISINCount (string offset, record number) pairs sorted by ISIN
+//	sedol index SEDOLCount pairs sorted by SEDOL
+//	arena      strings, each a uvarint length followed by the bytes; offset 0 is ""
+//	metadata   JSON-encoded Metadata, possibly empty
+//
+// Any change to the record layout must bump mappedVersion.
+
+const (
+	mappedMagic      = "LEMMAP01"
+	mappedVersion    = 1
+	mappedHeaderSize = 96
+
+	recCUSIP       = 0
+	recISIN        = 4
+	recSEDOL       = 8
+	recTicker      = 12
+	recEntity      = 16
+	recCountry     = 20
+	recIssueType   = 24
+	recCoupon      = 28
+	recMaturity    = 36
+	recIssueCode   = 44
+	recCurrency    = 48
+	recInception   = 52
+	recTermination = 56
+	recCIC         = 60
+	recSize        = 64
+
+	indexEntrySize = 8
+	noDate         = math.MinInt32
+)
+
+var (
+	ErrNotMapped = errors.New("not a mapped security master snapshot")
+	// ErrCorruptSnapshot is returned by lookups that find a string offset or record
+	// number outside the mapped snapshot
+	ErrCorruptSnapshot = errors.New("corrupt mapped snapshot")
+)
+
+type mappedHeader struct {
+	Magic       [8]byte
+	Version     uint32
+	RecordSize  uint32
+	Count       uint64
+	ISINCount   uint64
+	SEDOLCount  uint64
+	Records     uint64
+	ISINIndex   uint64
+	SEDOLIndex  uint64
+	Arena       uint64
+	Metadata    uint64
+	MetadataLen uint64
+}
+
+// MappedMaster is a read-only security master backed by a memory-mapped snapshot
+// written by WriteMappedSnapshot.  Lookups binary search the mapped file and only
+// allocate the Securities they return.
+type MappedMaster struct {
+	data    []byte
+	unmap   func() error
+	header  mappedHeader
+	records []byte
+	isins   []byte
+	sedols  []byte
+	arena   []byte
+	meta    *Metadata
+}
+
+// OpenMappedMaster maps the snapshot at path into memory
+func OpenMappedMaster(path string) (m *MappedMaster, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	m = &MappedMaster{}
+	m.data, m.unmap, err = mapFile(f)
+	if err != nil {
+		return nil, err
+	}
+	err = m.init()
+	if err != nil {
+		m.Close()
+		return nil, err
+	}
+	return
+}
+
+func (m *MappedMaster) init() error {
+	if len(m.data) < mappedHeaderSize {
+		return ErrNotMapped
+	}
+	err := binary.Read(bytes.NewReader(m.data[:mappedHeaderSize]), binary.LittleEndian, &m.header)
+	if err != nil || string(m.header.Magic[:]) != mappedMagic {
+		return ErrNotMapped
+	}
+	h := &m.header
+	if h.Version != mappedVersion {
+		return fmt.Errorf("unsupported mapped snapshot version %d", h.Version)
+	}
+	if h.RecordSize != recSize {
+		return fmt.Errorf("mapped snapshot records are %d bytes, want %d", h.RecordSize, recSize)
+	}
+	size := uint64(len(m.data))
+	if h.Count > size || h.ISINCount > size || h.SEDOLCount > size {
+		return fmt.Errorf("mapped snapshot truncated: %d records exceed %d bytes", h.Count, size)
+	}
+	section := func(start, length uint64) ([]byte, error) {
+		if start > size || length > size-start {
+			return nil, fmt.Errorf("mapped snapshot truncated: section at %d+%d exceeds %d bytes", start, length, size)
+		}
+		return m.data[start : start+length], nil
+	}
+	if m.records, err = section(h.Records, h.Count*uint64(h.RecordSize)); err != nil {
+		return err
+	}
+	if m.isins, err = section(h.ISINIndex, h.ISINCount*indexEntrySize); err != nil {
+		return err
+	}
+	if m.sedols, err = section(h.SEDOLIndex, h.SEDOLCount*indexEntrySize); err != nil {
+		return err
+	}
+	if m.arena, err = section(h.Arena, h.Metadata-h.Arena); err != nil {
+		return err
+	}
+	var meta []byte
+	if meta, err = section(h.Metadata, h.MetadataLen); err != nil {
+		return err
+	}
+	if len(meta) > 0 {
+		m.meta = &Metadata{}
+		if err = json.Unmarshal(meta, m.meta); err != nil {
+			return fmt.Errorf("mapped snapshot metadata: %s", err)
+		}
+	}
+	return nil
+}
+
+// Close unmaps the snapshot.  The master must not be used afterwards.
+func (m *MappedMaster) Close() error {
+	if m.unmap == nil {
+		return nil
+	}
+	err := m.unmap()
+	m.unmap = nil
+	m.data, m.records, m.isins, m.sedols, m.arena = nil, nil, nil, nil, nil
+	return err
+}
+
+// Len returns the number of securities in the snapshot
+func (m *MappedMaster) Len() int {
+	return int(m.header.Count)
+}
+
+// Describe returns the provenance of the data in the snapshot
+func (m *MappedMaster) Describe() (*Metadata, error) {
+	if m.meta == nil {
+		return nil, ErrNoMetadata
+	}
+	return m.meta, nil
+}
+
+// RecordCounts returns the number of securities and of ISINs and SEDOLs indexed,
+// keyed by the name of the equivalent bucket
+func (m *MappedMaster) RecordCounts() (map[string]int, error) {
+	return map[string]int{
+		DetailsBucket: int(m.header.Count),
+		IsinBucket:    int(m.header.ISINCount),
+		SedolBucket:   int(m.header.SEDOLCount),
+	}, nil
+}
+
+// str returns the arena string at off without copying it.  Offsets and lengths are
+// checked here rather than when the snapshot is opened, so opening stays independent
+// of its size.
+func (m *MappedMaster) str(off uint32) ([]byte, error) {
+	if uint64(off) >= uint64(len(m.arena)) {
+		return nil, ErrCorruptSnapshot
+	}
+	n, w := binary.Uvarint(m.arena[off:])
+	start := uint64(off) + uint64(w)
+	if w <= 0 || n > uint64(len(m.arena))-start {
+		return nil, ErrCorruptSnapshot
+	}
+	return m.arena[start : start+n], nil
+}
+
+func (m *MappedMaster) record(i int) []byte {
+	return m.records[i*recSize : (i+1)*recSize]
+}
+
+func (m *MappedMaster) field(rec []byte, at int) ([]byte, error) {
+	return m.str(binary.LittleEndian.Uint32(rec[at:]))
+}
+
+// searchCUSIP returns the number of the first record whose CUSIP satisfies f, as
+// sort.Search does
+func (m *MappedMaster) searchCUSIP(f func(cusip []byte) bool) (i int, err error) {
+	i = sort.Search(m.Len(), func(i int) bool {
+		cusip, e := m.field(m.record(i), recCUSIP)
+		if e != nil {
+			err = e
+			return true
+		}
+		return f(cusip)
+	})
+	return
+}
+
+// findCUSIP returns the record number of cusip, or -1
+func (m *MappedMaster) findCUSIP(cusip []byte) (int, error) {
+	i, err := m.searchCUSIP(func(c []byte) bool { return bytes.Compare(c, cusip) >= 0 })
+	if err != nil || i == m.Len() {
+		return -1, err
+	}
+	found, err := m.field(m.record(i), recCUSIP)
+	if err != nil || !bytes.Equal(found, cusip) {
+		return -1, err
+	}
+	return i, nil
+}
+
+// findIndexed returns the record number stored against key in a sorted index, or -1
+func (m *MappedMaster) findIndexed(index []byte, key []byte) (int, error) {
+	n := len(index) / indexEntrySize
+	var err error
+	entry := func(i int) []byte {
+		v, e := m.str(binary.LittleEndian.Uint32(index[i*indexEntrySize:]))
+		if e != nil {
+			err = e
+		}
+		return v
+	}
+	i := sort.Search(n, func(i int) bool {
+		return err != nil || bytes.Compare(entry(i), key) >= 0
+	})
+	if err != nil || i == n || !bytes.Equal(entry(i), key) {
+		return -1, err
+	}
+	if rec := binary.LittleEndian.Uint32(index[i*indexEntrySize+4:]); uint64(rec) < m.header.Count {
+		return int(rec), nil
+	}
+	return -1, ErrCorruptSnapshot
+}
+
+// security copies record i out of the snapshot
+func (m *MappedMaster) security(i int) (*Security, error) {
+	rec := m.record(i)
+	var err error
+	field := func(at int) string {
+		v, e := m.field(rec, at)
+		if e != nil {
+			err = e
+		}
+		return string(v)
+	}
+	s := &Security{
+		CUSIP:         field(recCUSIP),
+		ISIN:          field(recISIN),
+		SEDOL:         field(recSEDOL),
+		Ticker:        field(recTicker),
+		LegalEntityID: field(recEntity),
+		Country:       field(recCountry),
+		Currency:      field(recCurrency),
+		Inception:     field(recInception),
+		Termination:   field(recTermination),
+		CIC:           CIC(field(recCIC)),
+	}
+	s.Description.Ticker = s.Ticker
+	s.Description.IssueType = IssueType(binary.LittleEndian.Uint32(rec[recIssueType:]))
+	s.Description.IssueCode = field(recIssueCode)
+	s.Description.Coupon = math.Float64frombits(binary.LittleEndian.Uint64(rec[recCoupon:]))
+	if days := int32(binary.LittleEndian.Uint32(rec[recMaturity:])); days != noDate {
+		s.Description.Maturity = time.Unix(int64(days)*86400, 0).UTC()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get "hydrates" security details from one or more identifiers
+func (m *MappedMaster) Get(keys ...string) (response []*Security, err error) {
+	return m.GetContext(context.Background(), keys...)
+}
+
+// GetContext "hydrates" security details from one or more identifiers, giving up if
+// ctx is done first
+func (m *MappedMaster) GetContext(ctx context.Context, keys ...string) (response []*Security, err error) {
+	response = make([]*Security, len(keys))
+	for i, k := range keys {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+		if response[i], err = m.get(k); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// Lookup resolves each key independently
+func (m *MappedMaster) Lookup(ctx context.Context, keys ...string) ([]Result, error) {
+	return lookupEach(ctx, keys, func(key string) (*Security, error) {
+		return m.get(key)
+	})
+}
+
+func (m *MappedMaster) get(key string) (*Security, error) {
+	var i int
+	var err error
+	switch len(key) {
+	case 12:
+		i, err = m.findIndexed(m.isins, []byte(key))
+	case 7:
+		i, err = m.findIndexed(m.sedols, []byte(key))
+	default:
+		i, err = m.findCUSIP([]byte(key))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if i < 0 {
+		return &Security{}, nil
+	}
+	return m.security(i)
+}
+
+// mappedWriter accumulates the sections of a mapped snapshot
+type mappedWriter struct {
+	records bytes.Buffer
+	arena   bytes.Buffer
+	shared  map[string]uint32
+	isins   []mappedIndexEntry
+	sedols  []mappedIndexEntry
+	count   uint32
+}
+
+type mappedIndexEntry struct {
+	key    string
+	offset uint32
+	record uint32
+}
+
+// intern appends v to the arena, reusing the earlier copy of values that repeat
+// across many securities
+func (w *mappedWriter) intern(v string, shared bool) uint32 {
+	if len(v) == 0 {
+		return 0
+	}
+	if off, ok := w.shared[v]; ok && shared {
+		return off
+	}
+	off := uint32(w.arena.Len())
+	var n [binary.MaxVarintLen64]byte
+	w.arena.Write(n[:binary.PutUvarint(n[:], uint64(len(v)))])
+	w.arena.WriteString(v)
+	if shared {
+		w.shared[v] = off
+	}
+	return off
+}
+
+func (w *mappedWriter) add(s *Security) {
+	var rec [recSize]byte
+	le := binary.LittleEndian
+	le.PutUint32(rec[recCUSIP:], w.intern(s.CUSIP, false))
+	isin := w.intern(s.ISIN, false)
+	le.PutUint32(rec[recISIN:], isin)
+	sedol := w.intern(s.SEDOL, false)
+	le.PutUint32(rec[recSEDOL:], sedol)
+	le.PutUint32(rec[recTicker:], w.intern(s.Ticker, false))
+	le.PutUint32(rec[recEntity:], w.intern(s.LegalEntityID, true))
+	le.PutUint32(rec[recCountry:], w.intern(s.Country, true))
+	le.PutUint32(rec[recIssueType:], uint32(s.Description.IssueType))
+	le.PutUint64(rec[recCoupon:], math.Float64bits(s.Description.Coupon))
+	days := int32(noDate)
+	if !s.Description.Maturity.IsZero() {
+		days = int32(s.Description.Maturity.Unix() / 86400)
+	}
+	le.PutUint32(rec[recMaturity:], uint32(days))
+	le.PutUint32(rec[recIssueCode:], w.intern(s.Description.IssueCode, true))
+	le.PutUint32(rec[recCurrency:], w.intern(s.Currency, true))
+	le.PutUint32(rec[recInception:], w.intern(s.Inception, true))
+	le.PutUint32(rec[recTermination:], w.intern(s.Termination, true))
+	le.PutUint32(rec[recCIC:], w.intern(string(s.CIC), true))
+	w.records.Write(rec[:])
+	if len(s.ISIN) == 12 {
+		w.isins = append(w.isins, mappedIndexEntry{key: s.ISIN, offset: isin, record: w.count})
+	}
+	if len(s.SEDOL) == 7 {
+		w.sedols = append(w.sedols, mappedIndexEntry{key: s.SEDOL, offset: sedol, record: w.count})
+	}
+	w.count++
+}
+
+func writeMappedIndex(w io.Writer, entries []mappedIndexEntry) error {
+	sort.Sort(byMappedKey(entries))
+	var b [indexEntrySize]byte
+	for _, e := range entries {
+		binary.LittleEndian.PutUint32(b[:], e.offset)
+		binary.LittleEndian.PutUint32(b[4:], e.record)
+		if _, err := w.Write(b[:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type byMappedKey []mappedIndexEntry
+
+func (s byMappedKey) Len() int           { return len(s) }
+func (s byMappedKey) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byMappedKey) Less(i, j int) bool { return s[i].key < s[j].key }
+
+// WriteMappedSnapshot writes a mapped snapshot of the securities received from c,
+// which MUST be sorted in ascending order by CUSIP, to out
+func WriteMappedSnapshot(out io.Writer, c chan *Security, meta *Metadata) (err error) {
+	w := &mappedWriter{shared: make(map[string]uint32)}
+	w.arena.WriteByte(0) // the empty string
+	var prev string
+	for s := range c {
+		if w.count > 0 && s.CUSIP <= prev {
+			for range c {
+			}
+			return &OrderError{Position: int(w.count), CUSIP: s.CUSIP, Previous: prev}
+		}
+		prev = s.CUSIP
+		w.add(s)
+	}
+	if w.arena.Len() > math.MaxUint32 {
+		return fmt.Errorf("string arena of %d bytes exceeds the 4GB format limit", w.arena.Len())
+	}
+	var metaJSON []byte
+	if meta != nil {
+		metaJSON, err = json.Marshal(meta)
+		if err != nil {
+			return
+		}
+	}
+	h := mappedHeader{
+		Version:     mappedVersion,
+		RecordSize:  recSize,
+		Count:       uint64(w.count),
+		ISINCount:   uint64(len(w.isins)),
+		SEDOLCount:  uint64(len(w.sedols)),
+		Records:     mappedHeaderSize,
+		MetadataLen: uint64(len(metaJSON)),
+	}
+	copy(h.Magic[:], mappedMagic)
+	h.ISINIndex = h.Records + uint64(w.records.Len())
+	h.SEDOLIndex = h.ISINIndex + h.ISINCount*indexEntrySize
+	h.Arena = h.SEDOLIndex + h.SEDOLCount*indexEntrySize
+	h.Metadata = h.Arena + uint64(w.arena.Len())
+
+	bw := bufio.NewWriterSize(out, 1<<20)
+	if err = binary.Write(bw, binary.LittleEndian, &h); err != nil {
+		return
+	}
+	if _, err = bw.Write(make([]byte, mappedHeaderSize-binary.Size(&h))); err != nil {
+		return
+	}
+	if _, err = w.records.WriteTo(bw); err != nil {
+		return
+	}
+	if err = writeMappedIndex(bw, w.isins); err != nil {
+		return
+	}
+	if err = writeMappedIndex(bw, w.sedols); err != nil {
+		return
+	}
+	if _, err = w.arena.WriteTo(bw); err != nil {
+		return
+	}
+	if _, err = bw.Write(metaJSON); err != nil {
+		return
+	}
+	return bw.Flush()
+}
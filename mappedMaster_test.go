@@ -0,0 +1,223 @@
+package fast_lem
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func writeMappedFile(t testing.TB, securities []*Security, meta *Metadata) string {
+	f, err := ioutil.TempFile("", "mapped")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	c := make(chan *Security, len(securities))
+	for _, s := range securities {
+		c <- s
+	}
+	close(c)
+	err = WriteMappedSnapshot(f, c, meta)
+	if err != nil {
+		os.Remove(f.Name())
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+func TestMappedMaster(t *testing.T) {
+	securities := testSecurities()
+	path := writeMappedFile(t, securities, &Metadata{Rows: 3})
+	defer os.Remove(path)
+	m, err := OpenMappedMaster(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if m.Len() != len(securities) {
+		t.Errorf("Got %d securities, want %d", m.Len(), len(securities))
+	}
+	if meta, _ := m.Describe(); meta == nil || meta.Rows != 3 {
+		t.Errorf("Got metadata %+v, want Rows 3", meta)
+	}
+	keys := []string{"851500000", "US8515000006", "FDS010000", "B0YBKJ7", "B0YBKL9", "NOPE00000", "XX0000000000"}
+	want := []*Security{securities[0], securities[0], securities[1], securities[1], securities[2], &Security{}, &Security{}}
+	response, err := m.Get(keys...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range response {
+		got, _ := s.MarshalJSON()
+		expected, _ := want[i].MarshalJSON()
+		if !bytes.Equal(got, expected) {
+			t.Errorf("%s: got %s, want %s", keys[i], got, expected)
+		}
+	}
+	if !response[2].Description.Maturity.Equal(securities[1].Description.Maturity) {
+		t.Errorf("Got maturity %s, want %s", response[2].Description.Maturity, securities[1].Description.Maturity)
+	}
+}
+
+func TestMappedMasterCorrupt(t *testing.T) {
+	path := writeMappedFile(t, testSecurities(), nil)
+	defer os.Remove(path)
+	good, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	le := binary.LittleEndian
+	arena := le.Uint64(good[64:])
+	// open writes data patched by corrupt to a file and maps it
+	open := func(corrupt func(data []byte) []byte) (*MappedMaster, error) {
+		data := corrupt(append([]byte(nil), good...))
+		if err := ioutil.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return OpenMappedMaster(path)
+	}
+	for name, corrupt := range map[string]func([]byte) []byte{
+		"truncated":   func(data []byte) []byte { return data[:len(data)/2] },
+		"record size": func(data []byte) []byte { le.PutUint32(data[12:], recSize-20); return data },
+		"count":       func(data []byte) []byte { le.PutUint64(data[16:], 1<<62); return data },
+	} {
+		if m, err := open(corrupt); err == nil {
+			m.Close()
+			t.Errorf("%s: got no error opening the snapshot", name)
+		}
+	}
+	for name, corrupt := range map[string]func([]byte) []byte{
+		"string offset": func(data []byte) []byte {
+			le.PutUint32(data[mappedHeaderSize+recSize+recCUSIP:], 0xffffffff)
+			return data
+		},
+		"string length": func(data []byte) []byte {
+			// the last byte of the arena is a character, read as a length running past its end
+			le.PutUint32(data[mappedHeaderSize+recSize+recCUSIP:], uint32(len(data)-1-int(arena)))
+			return data
+		},
+		"record number": func(data []byte) []byte {
+			le.PutUint32(data[le.Uint64(data[48:])+4:], 3)
+			return data
+		},
+	} {
+		m, err := open(corrupt)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if _, err = m.Get("FDS010000", "US8515000006"); err != ErrCorruptSnapshot {
+			t.Errorf("%s: got %v, want %v", name, err, ErrCorruptSnapshot)
+		}
+		m.Close()
+	}
+}
+
+func TestMappedSnapshotRejectsUnsorted(t *testing.T) {
+	securities := testSecurities()
+	c := make(chan *Security, len(securities))
+	for i := len(securities) - 1; i >= 0; i-- {
+		c <- securities[i]
+	}
+	close(c)
+	if err := WriteMappedSnapshot(ioutil.Discard, c, nil); err == nil {
+		t.Error("Expected an error for securities out of CUSIP order")
+	}
+}
+
+const benchmarkUniverse = 200000
+
+func benchmarkSecurities() []*Security {
+	securities := make([]*Security, benchmarkUniverse)
+	for i := range securities {
+		securities[i] = New(fmt.Sprintf("%09d", i), fmt.Sprintf("US%09d0", i), fmt.Sprintf("B%06d", i),
+			"", fmt.Sprintf("%06d-E", i%5000), "BD", "4.25", "2027-07-01")
+	}
+	return securities
+}
+
+func benchmarkKeys() []string {
+	keys := make([]string, 0, 3000)
+	for i := 0; i < 1000; i++ {
+		n := (i * 7919) % benchmarkUniverse
+		keys = append(keys, fmt.Sprintf("%09d", n), fmt.Sprintf("US%09d0", n), fmt.Sprintf("B%06d", n))
+	}
+	return keys
+}
+
+func BenchmarkSecurityMasterGet(b *testing.B) {
+	c := make(chan *Security, benchmarkUniverse)
+	for _, s := range benchmarkSecurities() {
+		c <- s
+	}
+	close(c)
+	m, err := NewSecurityMaster(c)
+	if err != nil {
+		b.Fatal(err)
+	}
+	keys := benchmarkKeys()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Get(keys...)
+	}
+}
+
+func BenchmarkMappedMasterGet(b *testing.B) {
+	path := writeMappedFile(b, benchmarkSecurities(), nil)
+	defer os.Remove(path)
+	m, err := OpenMappedMaster(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer m.Close()
+	keys := benchmarkKeys()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Get(keys...)
+	}
+}
+
+func BenchmarkSecurityMasterLoad(b *testing.B) {
+	securities := testMasterSnapshot(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ReadSnapshot(bytes.NewReader(securities)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func testMasterSnapshot(b *testing.B) []byte {
+	c := make(chan *Security, benchmarkUniverse)
+	for _, s := range benchmarkSecurities() {
+		c <- s
+	}
+	close(c)
+	m, err := NewSecurityMaster(c)
+	if err != nil {
+		b.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	if err = m.WriteSnapshot(buf); err != nil {
+		b.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func BenchmarkMappedMasterOpen(b *testing.B) {
+	path := writeMappedFile(b, benchmarkSecurities(), nil)
+	defer os.Remove(path)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m, err := OpenMappedMaster(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		m.Close()
+	}
+}
@@ -1,10 +1,11 @@
 package fast_lem
 
 import (
-	"errors"
-	"io/ioutil"
-	"os"
+	"context"
+	"fmt"
+	"sort"
 
+	"github.com/boltdb/bolt"
 	"github.com/smartystreets/mafsa"
 )
 
@@ -13,19 +14,38 @@ type SecurityMaster struct {
 	Securities []*Security
 	ISINIndex  map[string]int
 	SEDOLIndex map[string]int
+	// Metadata describes the load the master was built from, if known
+	Metadata *Metadata
+	// Hierarchy is the corporate structure of the legal entities, if known
+	Hierarchy *Hierarchy
 }
 
-var (
-	ERR_COUNT_TOO_LOW = errors.New("Provided count was less than actual number of records")
-)
+// OrderError reports a security received out of ascending CUSIP order
+type OrderError struct {
+	Position int
+	CUSIP    string
+	Previous string
+}
+
+func (e *OrderError) Error() string {
+	if e.CUSIP == e.Previous {
+		return fmt.Sprintf("security %d: duplicate CUSIP %q", e.Position, e.CUSIP)
+	}
+	return fmt.Sprintf("security %d: CUSIP %q follows %q; securities must be sorted in ascending order by CUSIP",
+		e.Position, e.CUSIP, e.Previous)
+}
 
 // NewSecurityMaster returns an in-memory security master from a channel of securities which
-// MUST be sorted in ascending order by CUSIP
+// MUST be sorted in ascending order by CUSIP.  An *OrderError is returned for input that is
+// out of order or repeats a CUSIP.
 func NewSecurityMaster(securities chan *Security) (m *SecurityMaster, err error) {
 	m = &SecurityMaster{Securities: make([]*Security, 0), ISINIndex: make(map[string]int), SEDOLIndex: make(map[string]int)}
 	i := 0
 	bt := mafsa.New()
 	for s := range securities {
+		if i > 0 && s.CUSIP <= m.Securities[i-1].CUSIP {
+			return nil, &OrderError{Position: i, CUSIP: s.CUSIP, Previous: m.Securities[i-1].CUSIP}
+		}
 		err = bt.Insert(s.CUSIP)
 		if err != nil {
 			return
@@ -39,40 +59,129 @@ func NewSecurityMaster(securities chan *Security) (m *SecurityMaster, err error)
 		m.Securities = append(m.Securities, s)
 		i++
 	}
-	var tmp *os.File
-	tmp, err = ioutil.TempFile("", "mafsaDump")
+	bt.Finish()
+	var data []byte
+	data, err = bt.MarshalBinary()
 	if err != nil {
 		return
 	}
-	tmp.Close()
-	defer os.Remove(tmp.Name())
-	err = bt.Save(tmp.Name())
+	bt = nil
+	m.Index, err = new(mafsa.Decoder).Decode(data)
+	return
+}
+
+// NewSecurityMasterFromUnsorted returns an in-memory security master from a channel of
+// securities in any order.  The securities are buffered and sorted by CUSIP first, so
+// this needs more memory than NewSecurityMaster.
+func NewSecurityMasterFromUnsorted(securities chan *Security) (*SecurityMaster, error) {
+	var all []*Security
+	for s := range securities {
+		all = append(all, s)
+	}
+	sort.Sort(byCUSIP(all))
+	c := make(chan *Security, 10000)
+	go func() {
+		defer close(c)
+		for _, s := range all {
+			c <- s
+		}
+	}()
+	m, err := NewSecurityMaster(c)
+	for range c {
+		// drain the channel if NewSecurityMaster gave up early
+	}
+	return m, err
+}
+
+type byCUSIP []*Security
+
+func (s byCUSIP) Len() int           { return len(s) }
+func (s byCUSIP) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byCUSIP) Less(i, j int) bool { return s[i].CUSIP < s[j].CUSIP }
+
+// NewSecurityMasterFromBolt returns an in-memory security master holding every
+// Security in a database written by Storage
+func NewSecurityMasterFromBolt(db *bolt.DB) (m *SecurityMaster, err error) {
+	c := make(chan *Security, 10000)
+	done := make(chan error, 1)
+	go func() {
+		done <- ForEachSecurity(db, func(s *Security) error {
+			c <- s
+			return nil
+		})
+		close(c)
+	}()
+	m, err = NewSecurityMaster(c)
+	for range c {
+		// drain the channel if NewSecurityMaster gave up early
+	}
+	if iterErr := <-done; err == nil {
+		err = iterErr
+	}
 	if err != nil {
-		return
+		return nil, err
+	}
+	m.Metadata, err = ReadMetadata(db)
+	if err != nil && err != ErrNoMetadata {
+		return nil, err
+	}
+	m.Hierarchy, err = ReadHierarchy(db)
+	if err == ErrNoHierarchy {
+		err = nil
 	}
-	bt = nil
-	// debug.FreeOSMemory()
-	m.Index, err = mafsa.Load(tmp.Name())
 	return
 }
 
+// Describe returns the provenance of the data held by the master
+func (m *SecurityMaster) Describe() (*Metadata, error) {
+	if m.Metadata == nil {
+		return nil, ErrNoMetadata
+	}
+	return m.Metadata, nil
+}
+
+// RecordCounts returns the number of securities and of ISINs and SEDOLs indexed,
+// keyed by the name of the equivalent bucket
+func (m *SecurityMaster) RecordCounts() (map[string]int, error) {
+	counts := map[string]int{
+		DetailsBucket: len(m.Securities),
+		IsinBucket:    len(m.ISINIndex),
+		SedolBucket:   len(m.SEDOLIndex),
+	}
+	if m.Hierarchy != nil {
+		counts[EntityBucket] = m.Hierarchy.Len()
+	}
+	return counts, nil
+}
+
 // Get "hydrates" security details from one or more identifiers
 func (m *SecurityMaster) Get(keys ...string) (response []*Security, err error) {
-	size := len(keys)
-	response = make([]*Security, size)
-	i := 0
-	for _, k := range keys {
-		var s *Security
-		s, err = m.get(k)
+	return m.GetContext(context.Background(), keys...)
+}
+
+// GetContext "hydrates" security details from one or more identifiers, giving up if
+// ctx is done first
+func (m *SecurityMaster) GetContext(ctx context.Context, keys ...string) (response []*Security, err error) {
+	response = make([]*Security, len(keys))
+	for i, k := range keys {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+		response[i], err = m.get(k)
 		if err != nil {
-			return
+			return nil, err
 		}
-		response[i] = s
-		i++
 	}
 	return
 }
 
+// Lookup resolves each key independently
+func (m *SecurityMaster) Lookup(ctx context.Context, keys ...string) ([]Result, error) {
+	return lookupEach(ctx, keys, m.get)
+}
+
 func (m *SecurityMaster) get(key string) (s *Security, err error) {
 	s = &Security{}
 	switch len(key) {
@@ -95,5 +204,4 @@ func (m *SecurityMaster) get(key string) (s *Security, err error) {
 		}
 		return m.Securities[pos-1], nil
 	}
-	return
 }
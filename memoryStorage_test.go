@@ -0,0 +1,35 @@
+package fast_lem
+
+import "testing"
+
+func securityChan(securities ...*Security) chan *Security {
+	c := make(chan *Security, len(securities))
+	for _, s := range securities {
+		c <- s
+	}
+	close(c)
+	return c
+}
+
+func TestNewSecurityMasterOrder(t *testing.T) {
+	s := testSecurities()
+	_, err := NewSecurityMaster(securityChan(s[0], s[2], s[1]))
+	if oe, ok := err.(*OrderError); !ok || oe.Position != 2 || oe.CUSIP != s[1].CUSIP {
+		t.Errorf("Got error %v, want an OrderError at position 2", err)
+	}
+	_, err = NewSecurityMaster(securityChan(s[0], s[1], s[1]))
+	if oe, ok := err.(*OrderError); !ok || oe.CUSIP != oe.Previous {
+		t.Errorf("Got error %v, want a duplicate CUSIP OrderError", err)
+	}
+	var m *SecurityMaster
+	m, err = NewSecurityMasterFromUnsorted(securityChan(s[2], s[0], s[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, _ := m.Get(s[0].CUSIP, s[1].ISIN, s[2].SEDOL)
+	for i, r := range response {
+		if r.CUSIP != s[i].CUSIP {
+			t.Errorf("Key %d: got CUSIP '%s', want '%s'", i, r.CUSIP, s[i].CUSIP)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package fast_lem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// SchemaVersion identifies the layout of the buckets written by Store
+const SchemaVersion = 1
+
+// Version identifies the build of this code.  Override it at link time with
+// -ldflags "-X github.com/nycmonkey/fast_lem.Version=..."
+var Version = "dev"
+
+const metadataKey = `load`
+
+var (
+	ErrNoMetadata = errors.New("database has no load metadata")
+)
+
+// SourceFile identifies an input file consumed by a load
+type SourceFile struct {
+	Name   string
+	Size   int64
+	SHA256 string
+}
+
+// Metadata records the provenance of a database load
+type Metadata struct {
+	SchemaVersion int
+	CodeVersion   string
+	Sources       []SourceFile
+	Started       time.Time
+	Finished      time.Time
+	Rows          int
+	Rejects       int
+	// Counts maps issue type codes to the number of rows loaded of that type
+	Counts map[string]int
+	// PreviousRows is the row count of the load this one replaced, if any
+	PreviousRows int
+	// InvalidCICs counts the rows loaded with a CIC that is malformed or inconsistent
+	// with their issue type
+	InvalidCICs int `json:",omitempty"`
+	// Entities counts the legal entities loaded from the entity structure, if any
+	Entities int `json:",omitempty"`
+}
+
+// NewMetadata returns Metadata stamped with the current schema and code versions
+func NewMetadata() *Metadata {
+	return &Metadata{
+		SchemaVersion: SchemaVersion,
+		CodeVersion:   Version,
+		Started:       time.Now(),
+		Counts:        make(map[string]int),
+	}
+}
+
+// Describer is implemented by Getters that know how their data was loaded
+type Describer interface {
+	Describe() (*Metadata, error)
+}
+
+// RecordCounter is implemented by Getters that can count the records they hold,
+// keyed by bucket name
+type RecordCounter interface {
+	RecordCounts() (map[string]int, error)
+}
+
+// WriteMetadata records m in the metadata bucket, replacing any previous load
+func WriteMetadata(db *bolt.DB, m *Metadata) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(MetadataBucket))
+		if err != nil {
+			return fmt.Errorf("create bucket: %s", err)
+		}
+		return b.Put([]byte(metadataKey), data)
+	})
+}
+
+// ReadMetadata returns the metadata of the last load, or ErrNoMetadata for databases
+// built before metadata was recorded
+func ReadMetadata(db *bolt.DB) (m *Metadata, err error) {
+	err = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(MetadataBucket))
+		if b == nil {
+			return ErrNoMetadata
+		}
+		data := b.Get([]byte(metadataKey))
+		if data == nil {
+			return ErrNoMetadata
+		}
+		m = &Metadata{}
+		return json.Unmarshal(data, m)
+	})
+	return
+}
@@ -0,0 +1,278 @@
+package fast_lem
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LatencyBuckets are the upper bounds, in seconds, of the latency histograms
+var LatencyBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// KeyCountBuckets are the upper bounds of the keys-per-request histogram
+var KeyCountBuckets = []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000, 5000}
+
+// Metrics collects counters and histograms describing the server and its storage, and
+// exposes them in the Prometheus text format.  The methods of a nil *Metrics do
+// nothing, so instrumented code need not check whether metrics are enabled.
+type Metrics struct {
+	requests    *counterVec
+	latency     *histogramVec
+	keys        *histogramVec
+	lookups     *counterVec
+	readTx      *histogramVec
+	mu          sync.Mutex
+	collections []collector
+}
+
+type collector interface {
+	name() string
+	write(w io.Writer)
+}
+
+// NewMetrics returns Metrics with the server and storage metrics registered
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		requests: newCounterVec("lem_http_requests_total",
+			"HTTP requests served, by handler and status code.", "handler", "code"),
+		latency: newHistogramVec("lem_http_request_duration_seconds",
+			"Time taken to serve HTTP requests, by handler.", LatencyBuckets, "handler"),
+		keys: newHistogramVec("lem_query_keys",
+			"Number of keys in each query.", KeyCountBuckets),
+		lookups: newCounterVec("lem_lookups_total",
+			"Keys looked up, by identifier type and outcome.", "type", "status"),
+		readTx: newHistogramVec("lem_bolt_read_tx_duration_seconds",
+			"Time spent in Bolt read transactions.", LatencyBuckets),
+	}
+	m.collections = []collector{m.requests, m.latency, m.keys, m.lookups, m.readTx}
+	return m
+}
+
+// GaugeFunc registers a gauge whose value is read from fn at each scrape
+func (m *Metrics) GaugeFunc(name, help string, fn func() float64) {
+	m.addFunc(&metricFunc{metric: name, help: help, kind: "gauge", fn: fn})
+}
+
+// CounterFunc registers a counter whose value is read from fn at each scrape
+func (m *Metrics) CounterFunc(name, help string, fn func() float64) {
+	m.addFunc(&metricFunc{metric: name, help: help, kind: "counter", fn: fn})
+}
+
+func (m *Metrics) addFunc(f *metricFunc) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.collections = append(m.collections, f)
+	m.mu.Unlock()
+}
+
+// ObserveRequest records an HTTP request served by handler
+func (m *Metrics) ObserveRequest(handler string, code int, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.requests.inc(handler, strconv.Itoa(code))
+	m.latency.observe(d.Seconds(), handler)
+}
+
+// ObserveLookups records the size and outcomes of a batch lookup
+func (m *Metrics) ObserveLookups(results []Result) {
+	if m == nil {
+		return
+	}
+	m.keys.observe(float64(len(results)))
+	for _, r := range results {
+		m.lookups.inc(r.Type.String(), r.Status.String())
+	}
+}
+
+// ObserveReadTx records the duration of a Bolt read transaction
+func (m *Metrics) ObserveReadTx(d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.readTx.observe(d.Seconds())
+}
+
+// Handler responds with every metric in the Prometheus text exposition format
+func (m *Metrics) Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo writes every metric to w in the Prometheus text exposition format
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	collections := make([]collector, len(m.collections))
+	copy(collections, m.collections)
+	m.mu.Unlock()
+	sort.Slice(collections, func(i, j int) bool { return collections[i].name() < collections[j].name() })
+	cw := &countingWriter{w: w}
+	for _, c := range collections {
+		c.write(cw)
+	}
+	return cw.n, cw.err
+}
+
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
+
+type metricFunc struct {
+	metric, help, kind string
+	fn                 func() float64
+}
+
+func (f *metricFunc) name() string { return f.metric }
+
+func (f *metricFunc) write(w io.Writer) {
+	writeHeader(w, f.metric, f.help, f.kind)
+	fmt.Fprintf(w, "%s %s\n", f.metric, formatFloat(f.fn()))
+}
+
+// counterVec is a family of counters distinguished by label values
+type counterVec struct {
+	metric, help string
+	labels       []string
+	mu           sync.Mutex
+	values       map[string]uint64
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{metric: name, help: help, labels: labels, values: make(map[string]uint64)}
+}
+
+func (c *counterVec) name() string { return c.metric }
+
+func (c *counterVec) inc(labelValues ...string) {
+	k := strings.Join(labelValues, "\xff")
+	c.mu.Lock()
+	c.values[k]++
+	c.mu.Unlock()
+}
+
+func (c *counterVec) write(w io.Writer) {
+	writeHeader(w, c.metric, c.help, "counter")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, k := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %d\n", c.metric, formatLabels(c.labels, k), c.values[k])
+	}
+}
+
+// histogramVec is a family of histograms distinguished by label values
+type histogramVec struct {
+	metric, help string
+	labels       []string
+	buckets      []float64
+	mu           sync.Mutex
+	values       map[string]*histogram
+}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func newHistogramVec(name, help string, buckets []float64, labels ...string) *histogramVec {
+	return &histogramVec{metric: name, help: help, labels: labels, buckets: buckets, values: make(map[string]*histogram)}
+}
+
+func (h *histogramVec) name() string { return h.metric }
+
+func (h *histogramVec) observe(v float64, labelValues ...string) {
+	k := strings.Join(labelValues, "\xff")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hist, ok := h.values[k]
+	if !ok {
+		hist = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.values[k] = hist
+	}
+	// counts are cumulative, as exposed
+	for i := len(h.buckets) - 1; i >= 0 && v <= h.buckets[i]; i-- {
+		hist.counts[i]++
+	}
+	hist.count++
+	hist.sum += v
+}
+
+func (h *histogramVec) write(w io.Writer) {
+	writeHeader(w, h.metric, h.help, "histogram")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	keys := make([]string, 0, len(h.values))
+	for k := range h.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	labels := append(append([]string(nil), h.labels...), "le")
+	for _, k := range keys {
+		hist := h.values[k]
+		prefix := k
+		if len(h.labels) > 0 {
+			prefix += "\xff"
+		}
+		for i, b := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.metric, formatLabels(labels, prefix+formatFloat(b)), hist.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.metric, formatLabels(labels, prefix+"+Inf"), hist.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.metric, formatLabels(h.labels, k), formatFloat(hist.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.metric, formatLabels(h.labels, k), hist.count)
+	}
+}
+
+func writeHeader(w io.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func sortedKeys(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatLabels renders the label values joined in k as {name="value",...}
+func formatLabels(names []string, k string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	values := strings.Split(k, "\xff")
+	pairs := make([]string, len(names))
+	for i, n := range names {
+		pairs[i] = n + `="` + labelEscaper.Replace(values[i]) + `"`
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
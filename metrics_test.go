@@ -0,0 +1,46 @@
+package fast_lem
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInstrumentedQuery(t *testing.T) {
+	storage, cleanup := testStorage(t)
+	defer cleanup()
+	m := NewMetrics()
+	var logged bytes.Buffer
+	server := Server{Getter: NewInstrumentedGetter(storage.(*boltPersistance).db, m), Metrics: m}
+	h := Instrument("query", server.QueryHandler, m, NewAccessLog(&logged))
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest("POST", "/query", strings.NewReader(`{"Keys":["851500000","B0YBKJ7","NOPE00000",""]}`)))
+	if w.Code != 200 {
+		t.Fatalf("Got status %d, want 200", w.Code)
+	}
+	var e AccessLogEntry
+	if err := json.Unmarshal(logged.Bytes(), &e); err != nil {
+		t.Fatal(err, logged.String())
+	}
+	if e.Handler != "query" || e.Status != 200 || e.Keys != 4 || e.Found != 2 || e.NotFound != 1 || e.Invalid != 1 {
+		t.Errorf("Unexpected access log entry %s", logged.String())
+	}
+	var exposed bytes.Buffer
+	m.WriteTo(&exposed)
+	for _, want := range []string{
+		`lem_http_requests_total{handler="query",code="200"} 1`,
+		`lem_http_request_duration_seconds_count{handler="query"} 1`,
+		`lem_lookups_total{type="SEDOL",status="found"} 1`,
+		`lem_lookups_total{type="CUSIP",status="not_found"} 1`,
+		`lem_lookups_total{type="invalid",status="invalid"} 1`,
+		`lem_query_keys_bucket{le="2"} 0`,
+		`lem_query_keys_bucket{le="5"} 1`,
+		`lem_bolt_read_tx_duration_seconds_count 1`,
+	} {
+		if !strings.Contains(exposed.String(), want+"\n") {
+			t.Errorf("Metrics missing %s", want)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package fast_lem
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+// mapFile reads f into memory; snapshots are not memory mapped on this platform
+func mapFile(f *os.File) (data []byte, unmap func() error, err error) {
+	data, err = ioutil.ReadAll(f)
+	return data, func() error { return nil }, err
+}
@@ -0,0 +1,25 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package fast_lem
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile maps f read-only into memory
+func mapFile(f *os.File) (data []byte, unmap func() error, err error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return
+	}
+	if fi.Size() == 0 {
+		return nil, func() error { return nil }, nil
+	}
+	data, err = syscall.Mmap(int(f.Fd()), 0, int(fi.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return syscall.Munmap(data) }, nil
+}
@@ -0,0 +1,700 @@
+package fast_lem
+
+import (
+	"bytes"
+	"container/heap"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/pquerna/ffjson/ffjson"
+)
+
+const (
+	// DefaultSearchLimit is the page size of a search that does not give one
+	DefaultSearchLimit = 100
+	// MaxSearchLimit is the largest page a search may ask for
+	MaxSearchLimit = 1000
+)
+
+var (
+	ErrSearchUnsupported = errors.New("the storage cannot be searched")
+)
+
+// Filter selects Securities by their attributes.  Within each list a Security must
+// match any value; across them it must match all the lists and bounds given.
+type Filter struct {
+	// IssueTypes are issue type codes, such as MU
+	IssueTypes   []string
+	AssetClasses []AssetClass
+	Countries    []string
+	Currencies   []string
+	// CICCategories are CIC categories, such as 1, or sub-categories, such as 14
+	CICCategories []string
+	// CICQuotationCountries are the country codes at the start of the CIC, including
+	// XL and XT
+	CICQuotationCountries []string
+	// Entities are FactSet entity IDs; a Security matches if its legal entity is one
+	// of them or any entity beneath one of them in the corporate structure
+	Entities []string
+	// MaturityFrom and MaturityTo bound the maturity date, inclusively; a Security
+	// with no maturity date does not match either
+	MaturityFrom time.Time
+	MaturityTo   time.Time
+	// ExcludeTerminated excludes securities terminated as of AsOf, or now if AsOf is
+	// zero.  Each Security's Status is computed as of the same date.
+	ExcludeTerminated bool
+	AsOf              time.Time
+	// Limit is the page size: DefaultSearchLimit if zero, and at most MaxSearchLimit
+	Limit int
+	// After is the CUSIP after which the page starts, taken from SearchResponse.Next
+	After string
+
+	// under holds Entities and every entity beneath them once resolved against a
+	// corporate structure
+	under map[string]bool
+}
+
+// Searcher finds the Securities matching a Filter
+type Searcher interface {
+	Search(ctx context.Context, f *Filter) (*SearchResponse, error)
+}
+
+// Search runs f against g, which must be a Searcher
+func Search(ctx context.Context, g Getter, f *Filter) (*SearchResponse, error) {
+	if s, ok := g.(Searcher); ok {
+		return s.Search(ctx, f)
+	}
+	return nil, ErrSearchUnsupported
+}
+
+// ParseFilter reads a Filter from the query parameters issuetype, assetclass, country,
+// currency, cic, cic_country and entity, each repeated or comma-separated;
+// maturity_from, maturity_to and asof, in FactSetDateFormat; exclude_terminated; limit and
+// after
+func ParseFilter(q url.Values) (f *Filter, err error) {
+	f = &Filter{
+		IssueTypes:            params(q, "issuetype"),
+		Countries:             params(q, "country"),
+		Currencies:            params(q, "currency"),
+		CICCategories:         params(q, "cic"),
+		CICQuotationCountries: params(q, "cic_country"),
+		Entities:              params(q, "entity"),
+		After:                 q.Get("after"),
+	}
+	for _, class := range params(q, "assetclass") {
+		f.AssetClasses = append(f.AssetClasses, AssetClass(class))
+	}
+	dates := []struct {
+		name string
+		t    *time.Time
+	}{{"maturity_from", &f.MaturityFrom}, {"maturity_to", &f.MaturityTo}, {"asof", &f.AsOf}}
+	for _, d := range dates {
+		if v := q.Get(d.name); len(v) > 0 {
+			*d.t, err = time.Parse(FactSetDateFormat, v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q; expected YYYY-MM-DD", d.name, v)
+			}
+		}
+	}
+	if v := q.Get("exclude_terminated"); len(v) > 0 {
+		f.ExcludeTerminated, err = strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude_terminated %q", v)
+		}
+	}
+	if v := q.Get("limit"); len(v) > 0 {
+		f.Limit, err = strconv.Atoi(v)
+		if err != nil || f.Limit < 0 || f.Limit > MaxSearchLimit {
+			return nil, fmt.Errorf("invalid limit %q; expected 1 to %d", v, MaxSearchLimit)
+		}
+	}
+	return f, nil
+}
+
+func params(q url.Values, name string) (values []string) {
+	for _, v := range q[name] {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); len(s) > 0 {
+				values = append(values, s)
+			}
+		}
+	}
+	return
+}
+
+// Values encodes f as the query parameters read by ParseFilter
+func (f *Filter) Values() url.Values {
+	q := url.Values{}
+	set := func(name string, values []string) {
+		if len(values) > 0 {
+			q.Set(name, strings.Join(values, ","))
+		}
+	}
+	set("issuetype", f.IssueTypes)
+	var classes []string
+	for _, class := range f.AssetClasses {
+		classes = append(classes, string(class))
+	}
+	set("assetclass", classes)
+	set("country", f.Countries)
+	set("currency", f.Currencies)
+	set("cic", f.CICCategories)
+	set("cic_country", f.CICQuotationCountries)
+	set("entity", f.Entities)
+	for name, t := range map[string]time.Time{"maturity_from": f.MaturityFrom, "maturity_to": f.MaturityTo, "asof": f.AsOf} {
+		if !t.IsZero() {
+			q.Set(name, t.Format(FactSetDateFormat))
+		}
+	}
+	if f.ExcludeTerminated {
+		q.Set("exclude_terminated", "true")
+	}
+	if f.Limit > 0 {
+		q.Set("limit", strconv.Itoa(f.Limit))
+	}
+	if len(f.After) > 0 {
+		q.Set("after", f.After)
+	}
+	return q
+}
+
+// filterFields maps the entitled field that each part of a Filter reveals to whether
+// the part is set
+func (f *Filter) filterFields() map[string]bool {
+	return map[string]bool{
+		"Description": len(f.IssueTypes) > 0 || len(f.AssetClasses) > 0 || !f.MaturityFrom.IsZero() ||
+			!f.MaturityTo.IsZero(),
+		"Country":       len(f.Countries) > 0,
+		"Currency":      len(f.Currencies) > 0,
+		"CIC":           len(f.CICCategories) > 0 || len(f.CICQuotationCountries) > 0,
+		"Status":        f.ExcludeTerminated,
+		"LegalEntityId": len(f.Entities) > 0,
+	}
+}
+
+func (f *Filter) limit() int {
+	switch {
+	case f.Limit <= 0:
+		return DefaultSearchLimit
+	case f.Limit > MaxSearchLimit:
+		return MaxSearchLimit
+	}
+	return f.Limit
+}
+
+// resolveEntities returns a copy of f whose Entities also match the entities beneath
+// them listed by descendants
+func (f *Filter) resolveEntities(descendants func(id string) ([]string, error)) (*Filter, error) {
+	if len(f.Entities) == 0 {
+		return f, nil
+	}
+	resolved := *f
+	resolved.under = make(map[string]bool)
+	for _, id := range f.Entities {
+		ids, err := descendants(id)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			resolved.under[id] = true
+		}
+	}
+	return &resolved, nil
+}
+
+// Match reports whether s passes f, as of asOf if f.ExcludeTerminated is set.  Unless
+// f was resolved against a corporate structure by a Search, Entities match only the
+// entities named.
+func (f *Filter) Match(s *Security, asOf time.Time) bool {
+	if len(f.IssueTypes) > 0 && !contains(f.IssueTypes, s.Description.Code()) {
+		return false
+	}
+	if len(f.AssetClasses) > 0 {
+		class := s.Description.IssueTypeInfo().AssetClass
+		found := false
+		for _, c := range f.AssetClasses {
+			found = found || strings.EqualFold(string(c), string(class))
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(f.Countries) > 0 && !contains(f.Countries, s.Country) {
+		return false
+	}
+	if len(f.Currencies) > 0 && !contains(f.Currencies, s.Currency) {
+		return false
+	}
+	if len(f.Entities) > 0 {
+		if f.under != nil && !f.under[s.LegalEntityID] {
+			return false
+		}
+		if f.under == nil && !contains(f.Entities, s.LegalEntityID) {
+			return false
+		}
+	}
+	if len(f.CICCategories) > 0 || len(f.CICQuotationCountries) > 0 {
+		cic, err := s.CIC.Decode()
+		if err != nil {
+			return false
+		}
+		if len(f.CICQuotationCountries) > 0 && !contains(f.CICQuotationCountries, cic.QuotationCountry) {
+			return false
+		}
+		found := len(f.CICCategories) == 0
+		for _, c := range f.CICCategories {
+			found = found || strings.HasPrefix(cic.SubCategory, strings.ToUpper(c))
+		}
+		if !found {
+			return false
+		}
+	}
+	m := s.Description.Maturity
+	if !f.MaturityFrom.IsZero() && (m.IsZero() || m.Before(f.MaturityFrom)) {
+		return false
+	}
+	if !f.MaturityTo.IsZero() && (m.IsZero() || m.After(f.MaturityTo)) {
+		return false
+	}
+	return !f.ExcludeTerminated || s.Lifecycle(asOf) != LifecycleTerminated
+}
+
+func (f *Filter) asOf() time.Time {
+	if f.AsOf.IsZero() {
+		return time.Now()
+	}
+	return f.AsOf
+}
+
+// page collects the Securities returned by next, which yields candidates in ascending
+// order by CUSIP until it returns nil, that match f, with their Status as of f.AsOf
+func (f *Filter) page(ctx context.Context, next func() (*Security, error)) (*SearchResponse, error) {
+	limit := f.limit()
+	asOf := f.asOf()
+	response := &SearchResponse{Results: make([]*Security, 0)}
+	for i := 0; ; i++ {
+		if i%1000 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		s, err := next()
+		if err != nil {
+			return nil, err
+		}
+		if s == nil {
+			return response, nil
+		}
+		if !f.Match(s, asOf) {
+			continue
+		}
+		if len(response.Results) == limit {
+			response.Next = response.Results[limit-1].CUSIP
+			return response, nil
+		}
+		response.Results = append(response.Results, s.AsOf(asOf))
+	}
+}
+
+// Search pages through the Securities matching f.  The most selective of the
+// secondary indexes selected by f supplies the candidates, from f.After on; without
+// one every Security after f.After is read.
+func (bp *boltPersistance) Search(ctx context.Context, f *Filter) (response *SearchResponse, err error) {
+	err = bp.view(func(tx *bolt.Tx) error {
+		f, err := f.resolveEntities(func(id string) ([]string, error) { return entityDescendants(ctx, tx, id) })
+		if err != nil {
+			return err
+		}
+		sel, err := mostSelective(tx, f)
+		if err != nil {
+			return err
+		}
+		details := tx.Bucket([]byte(DetailsBucket))
+		if sel == nil {
+			c := details.Cursor()
+			k, v := c.Seek([]byte(f.After))
+			if k != nil && string(k) == f.After {
+				k, v = c.Next()
+			}
+			response, err = f.page(ctx, func() (*Security, error) {
+				if k == nil {
+					return nil, nil
+				}
+				encoded := v
+				k, v = c.Next()
+				return decodeSecurity(encoded)
+			})
+			return err
+		}
+		w := sel.walk(f.After)
+		response, err = f.page(ctx, func() (*Security, error) {
+			for {
+				cusip, ok := w.next()
+				if !ok {
+					return nil, nil
+				}
+				if encoded := details.Get([]byte(cusip)); encoded != nil {
+					return decodeSecurity(encoded)
+				}
+			}
+		})
+		return err
+	})
+	return
+}
+
+// selectivityCap bounds the entries counted in each secondary index selected by a
+// search when choosing the one to walk
+const selectivityCap = 10000
+
+// indexSelection is the part of a secondary index selected by a Filter: the entries
+// beginning with each of prefixes
+type indexSelection struct {
+	b        *bolt.Bucket
+	prefixes [][]byte
+}
+
+// count returns the number of entries selected, counting no further than max
+func (sel *indexSelection) count(max int) int {
+	n := 0
+	for _, p := range sel.prefixes {
+		c := sel.b.Cursor()
+		for k, _ := c.Seek(p); k != nil && bytes.HasPrefix(k, p); k, _ = c.Next() {
+			if n++; n >= max {
+				return n
+			}
+		}
+	}
+	return n
+}
+
+// walk returns the CUSIPs selected after after, in ascending order
+func (sel *indexSelection) walk(after string) *indexWalk {
+	w := &indexWalk{}
+	for _, p := range sel.prefixes {
+		h := &indexHead{c: sel.b.Cursor(), prefix: p}
+		k, _ := h.c.Seek(append(p[:len(p):len(p)], after...))
+		ok := h.set(k)
+		if ok && h.cusip == after {
+			k, _ = h.c.Next()
+			ok = h.set(k)
+		}
+		if ok {
+			*w = append(*w, h)
+		}
+	}
+	heap.Init(w)
+	return w
+}
+
+// indexHead is the next entry of one prefix of an indexSelection
+type indexHead struct {
+	c      *bolt.Cursor
+	prefix []byte
+	cusip  string
+}
+
+// set moves h to the entry k, and reports whether k is still within h's prefix
+func (h *indexHead) set(k []byte) bool {
+	if k == nil || !bytes.HasPrefix(k, h.prefix) {
+		return false
+	}
+	h.cusip = string(k[len(h.prefix):])
+	return true
+}
+
+// indexWalk merges the prefixes of an indexSelection into ascending order by CUSIP.
+// It is a heap of the next entry of each prefix.
+type indexWalk []*indexHead
+
+func (w indexWalk) Len() int            { return len(w) }
+func (w indexWalk) Less(i, j int) bool  { return w[i].cusip < w[j].cusip }
+func (w indexWalk) Swap(i, j int)       { w[i], w[j] = w[j], w[i] }
+func (w *indexWalk) Push(x interface{}) { *w = append(*w, x.(*indexHead)) }
+func (w *indexWalk) Pop() interface{} {
+	old := *w
+	h := old[len(old)-1]
+	*w = old[:len(old)-1]
+	return h
+}
+
+// next returns the next CUSIP, or false once every prefix is exhausted
+func (w *indexWalk) next() (string, bool) {
+	if w.Len() == 0 {
+		return "", false
+	}
+	h := (*w)[0]
+	cusip := h.cusip
+	if k, _ := h.c.Next(); h.set(k) {
+		heap.Fix(w, 0)
+	} else {
+		heap.Pop(w)
+	}
+	return cusip, true
+}
+
+// mostSelective returns the secondary index selection of f with the fewest entries,
+// counted up to selectivityCap, or nil if f selects none
+func mostSelective(tx *bolt.Tx, f *Filter) (*indexSelection, error) {
+	issueTypes := f.IssueTypes
+	if len(issueTypes) == 0 && len(f.AssetClasses) > 0 {
+		for _, info := range IssueTypes() {
+			for _, class := range f.AssetClasses {
+				if strings.EqualFold(string(class), string(info.AssetClass)) {
+					issueTypes = append(issueTypes, info.Code)
+				}
+			}
+		}
+		if len(issueTypes) == 0 {
+			// no issue type is in the asset classes, so nothing matches
+			return &indexSelection{}, nil
+		}
+	}
+	var entities []string
+	for id := range f.under {
+		entities = append(entities, id)
+	}
+	bucket := func(name string) (*bolt.Bucket, error) {
+		b := tx.Bucket([]byte(name))
+		if b == nil {
+			return nil, fmt.Errorf("bucket %s not found; reload the database to search it", name)
+		}
+		return b, nil
+	}
+	var sels []*indexSelection
+	for _, selected := range []struct {
+		bucket string
+		values []string
+	}{
+		{IssueTypeIndexBucket, issueTypes},
+		{CountryIndexBucket, f.Countries},
+		{CurrencyIndexBucket, f.Currencies},
+		{EntityIndexBucket, entities},
+	} {
+		if len(selected.values) == 0 {
+			continue
+		}
+		b, err := bucket(selected.bucket)
+		if err != nil {
+			return nil, err
+		}
+		sel := &indexSelection{b: b}
+		for _, v := range selected.values {
+			sel.prefixes = append(sel.prefixes, []byte(v+"\x00"))
+		}
+		sels = append(sels, sel)
+	}
+	if !f.MaturityFrom.IsZero() || !f.MaturityTo.IsZero() {
+		b, err := bucket(MaturityIndexBucket)
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, maturitySelection(b, f.MaturityFrom, f.MaturityTo))
+	}
+	if len(sels) == 0 {
+		return nil, nil
+	}
+	best, fewest := sels[0], sels[0].count(selectivityCap)
+	for _, sel := range sels[1:] {
+		if n := sel.count(fewest); n < fewest {
+			best, fewest = sel, n
+		}
+	}
+	return best, nil
+}
+
+// maturitySelection selects the entries of the maturity index b from each distinct
+// date between from and to, inclusive; either may be zero to leave it open
+func maturitySelection(b *bolt.Bucket, from, to time.Time) *indexSelection {
+	sel := &indexSelection{b: b}
+	var last []byte
+	if !to.IsZero() {
+		last = []byte(to.Format(FactSetDateFormat))
+	}
+	c := b.Cursor()
+	k, _ := c.First()
+	if !from.IsZero() {
+		k, _ = c.Seek([]byte(from.Format(FactSetDateFormat)))
+	}
+	for k != nil {
+		i := bytes.IndexByte(k, 0)
+		if i < 0 {
+			k, _ = c.Next()
+			continue
+		}
+		date := string(k[:i])
+		if last != nil && date > string(last) {
+			break
+		}
+		sel.prefixes = append(sel.prefixes, []byte(date+"\x00"))
+		// skip the rest of the date's entries
+		k, _ = c.Seek([]byte(date + "\x01"))
+	}
+	return sel
+}
+
+// scanIndex appends to cusips the CUSIPs of the index entries from the first key at or
+// after from up to the last key beginning with to, or the last key if to is nil
+func scanIndex(b *bolt.Bucket, from, to []byte, cusips []string) []string {
+	c := b.Cursor()
+	k, _ := c.First()
+	if from != nil {
+		k, _ = c.Seek(from)
+	}
+	for ; k != nil; k, _ = c.Next() {
+		if to != nil && bytes.Compare(k, to) > 0 && !bytes.HasPrefix(k, to) {
+			break
+		}
+		if i := bytes.IndexByte(k, 0); i >= 0 {
+			cusips = append(cusips, string(k[i+1:]))
+		}
+	}
+	return cusips
+}
+
+// Search pages through the Securities matching f, reading every Security after
+// f.After
+func (m *SecurityMaster) Search(ctx context.Context, f *Filter) (*SearchResponse, error) {
+	f, err := f.resolveEntities(func(id string) ([]string, error) { return m.Descendants(ctx, id) })
+	if err != nil {
+		return nil, err
+	}
+	i := sort.Search(len(m.Securities), func(i int) bool { return m.Securities[i].CUSIP > f.After })
+	return f.page(ctx, func() (*Security, error) {
+		if i == len(m.Securities) {
+			return nil, nil
+		}
+		i++
+		return m.Securities[i-1], nil
+	})
+}
+
+// Search pages through the Securities matching f, reading every Security after
+// f.After.  A mapped snapshot holds no corporate structure, so f may not select
+// Entities.
+func (m *MappedMaster) Search(ctx context.Context, f *Filter) (*SearchResponse, error) {
+	if len(f.Entities) > 0 {
+		return nil, ErrNoHierarchy
+	}
+	after := []byte(f.After)
+	n := m.Len()
+	i, err := m.searchCUSIP(func(cusip []byte) bool { return bytes.Compare(cusip, after) > 0 })
+	if err != nil {
+		return nil, err
+	}
+	return f.page(ctx, func() (*Security, error) {
+		if i == n {
+			return nil, nil
+		}
+		i++
+		return m.security(i - 1)
+	})
+}
+
+// entitledSearchResponse is a SearchResponse whose Securities have been redacted
+type entitledSearchResponse struct {
+	Results      []json.Marshaler
+	Next         string   `json:",omitempty"`
+	DeniedFields []string `json:",omitempty"`
+}
+
+// SearchHandler responds to a GET request with the page of Securities matching the
+// Filter in its query parameters; see ParseFilter.  Clients may not filter on fields
+// they are not entitled to see, and clients not entitled to CUSIPs are given opaque
+// page tokens instead of the CUSIP each page ends with.
+func (s Server) SearchHandler(w http.ResponseWriter, r *http.Request) {
+	f, err := ParseFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	format, err := ResponseFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	entitlement := EntitlementFrom(r.Context())
+	if entitlement.RestrictsFields() {
+		for field, filtered := range f.filterFields() {
+			if filtered && !contains(entitlement.Fields, field) {
+				http.Error(w, "Not entitled to filter on "+field, http.StatusForbidden)
+				return
+			}
+		}
+	}
+	hideCUSIPs := entitlement.RestrictsFields() && !contains(entitlement.Fields, "Cusip")
+	if hideCUSIPs && len(f.After) > 0 {
+		f.After, err = s.openCursor(f.After)
+		if err != nil {
+			http.Error(w, "Invalid after: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	ctx := r.Context()
+	if s.LookupTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.LookupTimeout)
+		defer cancel()
+	}
+	response, err := Search(ctx, s.Getter, f)
+	switch err {
+	case nil:
+	case context.DeadlineExceeded:
+		http.Error(w, "Search timed out after "+s.LookupTimeout.String(), http.StatusGatewayTimeout)
+		return
+	case context.Canceled:
+		// the client has gone away
+		return
+	case ErrSearchUnsupported, ErrNoHierarchy:
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	case ErrNotLoaded:
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Vary", "Accept")
+	if format == StructuredFormat {
+		for i, sec := range response.Results {
+			response.Results[i] = sec.Structured()
+		}
+	}
+	var js []byte
+	if entitlement.RestrictsFields() {
+		redacted := &entitledSearchResponse{
+			Results:      make([]json.Marshaler, len(response.Results)),
+			Next:         response.Next,
+			DeniedFields: entitlement.DeniedFields(),
+		}
+		if hideCUSIPs && len(response.Next) > 0 {
+			redacted.Next, err = s.sealCursor(response.Next)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		for i, sec := range response.Results {
+			redacted.Results[i] = entitlement.Redact(sec)
+		}
+		js, err = json.Marshal(redacted)
+	} else {
+		js, err = ffjson.Marshal(response)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
@@ -0,0 +1,146 @@
+package fast_lem
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	storage, cleanup := testStorage(t)
+	defer cleanup()
+	path := writeMappedFile(t, testSecurities(), nil)
+	defer os.Remove(path)
+	mapped, err := OpenMappedMaster(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mapped.Close()
+	s := testSecurities()
+	for query, want := range map[string][]string{
+		"assetclass=Fixed+Income":                          {s[0].CUSIP, s[1].CUSIP},
+		"assetclass=fixed+income&maturity_from=2008-01-01": {s[1].CUSIP},
+		"exclude_terminated=true&asof=2008-01-01":          {s[1].CUSIP, s[2].CUSIP},
+		"issuetype=EQ,MU":                                  {s[0].CUSIP, s[2].CUSIP},
+		"issuetype=EQ&maturity_to=2020-01-01":              {},
+		"limit=2&after=" + s[0].CUSIP:                      {s[1].CUSIP, s[2].CUSIP},
+	} {
+		q, _ := url.ParseQuery(query)
+		f, err := ParseFilter(q)
+		if err != nil {
+			t.Fatalf("%s: %s", query, err)
+		}
+		for _, g := range []Getter{storage, testMaster(t), mapped} {
+			response, err := Search(context.Background(), g, f)
+			if err != nil {
+				t.Fatalf("%s: %s", query, err)
+			}
+			got := []string{}
+			for _, sec := range response.Results {
+				got = append(got, sec.CUSIP)
+			}
+			if !reflect.DeepEqual(got, want) || len(response.Next) > 0 {
+				t.Errorf("%s with %T: got %v, next %q, want %v", query, g, got, response.Next, want)
+			}
+		}
+	}
+}
+
+func TestSearchPages(t *testing.T) {
+	storage, cleanup := testStorage(t)
+	defer cleanup()
+	s := testSecurities()
+	for query, want := range map[string][]string{
+		"issuetype=EQ,MU,LN":                     {s[0].CUSIP, s[1].CUSIP, s[2].CUSIP},
+		"maturity_from=2000-01-01":               {s[0].CUSIP, s[1].CUSIP},
+		"maturity_to=2007-07-01&issuetype=MU,LN": {s[0].CUSIP},
+		"assetclass=Derivative":                  {},
+	} {
+		q, _ := url.ParseQuery(query + "&limit=1")
+		f, err := ParseFilter(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for {
+			response, err := Search(context.Background(), storage, f)
+			if err != nil {
+				t.Fatalf("%s: %s", query, err)
+			}
+			for _, sec := range response.Results {
+				got = append(got, sec.CUSIP)
+			}
+			if len(response.Next) == 0 {
+				break
+			}
+			f.After = response.Next
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v one page at a time, want %v", query, got, want)
+		}
+	}
+}
+
+func TestSearchHandler(t *testing.T) {
+	storage, cleanup := testStorage(t)
+	defer cleanup()
+	server := Server{Getter: NewSwappable(storage)}
+	search := func(query string) (int, string) {
+		w := httptest.NewRecorder()
+		server.SearchHandler(w, httptest.NewRequest("GET", "/search?"+query, nil))
+		return w.Code, w.Body.String()
+	}
+	code, body := search("limit=1")
+	if code != 200 || !strings.HasSuffix(body, `"Next":"851500000"}`) {
+		t.Errorf("Got %d %s, want the first page", code, body)
+	}
+	code, body = search("limit=1&after=FDS010000&format=structured")
+	if code != 200 || !strings.Contains(body, `"Cusip":"FDS020000"`) ||
+		!strings.Contains(body, `"AssetClass":"Equity"`) || strings.Contains(body, "Next") {
+		t.Errorf("Got %d %s, want the last page", code, body)
+	}
+	if code, body = search("maturity_from=2007"); code != 400 {
+		t.Errorf("Got %d %s for a bad date, want 400", code, body)
+	}
+	registry, err := NewClientRegistry(&Client{
+		Name:        "isins",
+		Keys:        []string{"secret"},
+		Entitlement: Entitlement{Fields: []string{"ISIN"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entitled := func(query string) (int, *SearchResponse) {
+		r := httptest.NewRequest("GET", "/search?"+query, nil)
+		r.Header.Set("X-API-Key", "secret")
+		w := httptest.NewRecorder()
+		Guard(registry, nil, server.SearchHandler)(w, r)
+		response := &SearchResponse{}
+		if w.Code == 200 {
+			if err := json.Unmarshal(w.Body.Bytes(), response); err != nil {
+				t.Fatal(err, w.Body)
+			}
+		}
+		return w.Code, response
+	}
+	code, page := entitled("limit=1")
+	if code != 200 || len(page.Next) == 0 || page.Next == "851500000" {
+		t.Errorf("Got %d, next %q for an ISIN-only client, want an opaque page token", code, page.Next)
+	}
+	code, page = entitled("limit=1&after=" + page.Next)
+	if code != 200 || len(page.Results) != 1 || page.Results[0].ISIN != "USFDS0100006" {
+		t.Errorf("Got %d %+v following the page token, want the second security", code, page)
+	}
+	if code, _ = entitled("after=851500000"); code != 400 {
+		t.Errorf("Got %d for a CUSIP as the page token of an ISIN-only client, want 400", code)
+	}
+	server.Getter = NewSwappable(nil)
+	if code, body = search(""); code != 503 {
+		t.Errorf("Got %d %s before loading, want 503", code, body)
+	}
+}
@@ -10,13 +10,24 @@ import (
 	"github.com/pquerna/ffjson/ffjson"
 )
 
+// FactSetDateFormat is the layout of dates in FactSet files, and of the dates the API
+// accepts and returns
 const FactSetDateFormat = `2006-01-02`
 const DescriptionDateFormat = `2006/01/02`
 
+// Description formats, selected per request
+const (
+	// LegacyFormat marshals a Description as a single formatted string
+	LegacyFormat = `legacy`
+	// StructuredFormat marshals a Description as a StructuredDescription
+	StructuredFormat = `structured`
+)
+
 func NewDescription(code, ticker, coupon, maturity string) (d *Description, err error) {
 	d = &Description{
 		Ticker:    ticker,
 		IssueType: IssueTypeFromString(code),
+		IssueCode: code,
 	}
 	if len(coupon) > 0 {
 		d.Coupon, err = strconv.ParseFloat(coupon, 64)
@@ -33,13 +44,25 @@ func NewDescription(code, ticker, coupon, maturity string) (d *Description, err
 	return
 }
 
+// New returns a Security, panicking if the coupon or maturity cannot be parsed
 func New(cusip, isin, sedol, ticker, entityID, issueTypeCode, coupon,
 	maturity string) (s *Security) {
-	desc, err := NewDescription(issueTypeCode, ticker, coupon, maturity)
+	s, err := NewSecurity(cusip, isin, sedol, ticker, entityID, issueTypeCode, coupon, maturity)
 	if err != nil {
 		panic(err)
 	}
-	return &Security{
+	return s
+}
+
+// NewSecurity returns a Security, or an error if the coupon or maturity cannot be parsed
+func NewSecurity(cusip, isin, sedol, ticker, entityID, issueTypeCode, coupon,
+	maturity string) (s *Security, err error) {
+	var desc *Description
+	desc, err = NewDescription(issueTypeCode, ticker, coupon, maturity)
+	if err != nil {
+		return
+	}
+	s = &Security{
 		LegalEntityID: entityID,
 		CUSIP:         cusip,
 		ISIN:          isin,
@@ -47,6 +70,7 @@ func New(cusip, isin, sedol, ticker, entityID, issueTypeCode, coupon,
 		Ticker:        ticker,
 		Description:   *desc,
 	}
+	return
 }
 
 // ffjson: skip
@@ -55,9 +79,65 @@ type Description struct {
 	Coupon    float64
 	Maturity  time.Time
 	Ticker    string
+	// IssueCode is the issue type code as loaded, kept even if IssueType does not
+	// recognise it.  It is empty in databases written before it was added.
+	IssueCode string
+	// structured selects StructuredFormat for MarshalJSON
+	structured bool
+}
+
+// Code returns the issue type code as loaded
+func (d Description) Code() string {
+	if len(d.IssueCode) > 0 {
+		return d.IssueCode
+	}
+	return d.IssueType.Code()
 }
 
+// IssueTypeInfo describes d's issue type.  Codes that are neither built in nor
+// registered are labelled N/A.
+func (d Description) IssueTypeInfo() IssueTypeInfo {
+	code := d.Code()
+	if info, ok := LookupIssueType(code); ok {
+		return info
+	}
+	return IssueTypeInfo{Code: code, Label: NA.String()}
+}
+
+// StructuredDescription is the StructuredFormat representation of a Description
+type StructuredDescription struct {
+	// IssueType is the FactSet issue type code, e.g. BD
+	IssueType      string `json:",omitempty"`
+	IssueTypeLabel string
+	AssetClass     AssetClass `json:",omitempty"`
+	Ticker         string     `json:",omitempty"`
+	Coupon         float64    `json:",omitempty"`
+	// Maturity is an ISO 8601 date
+	Maturity string `json:",omitempty"`
+}
+
+// Structured returns d in StructuredFormat
+func (d Description) Structured() *StructuredDescription {
+	info := d.IssueTypeInfo()
+	sd := &StructuredDescription{
+		IssueType:      info.Code,
+		IssueTypeLabel: info.Label,
+		AssetClass:     info.AssetClass,
+		Ticker:         d.Ticker,
+		Coupon:         d.Coupon,
+	}
+	if !d.Maturity.IsZero() {
+		sd.Maturity = d.Maturity.Format(FactSetDateFormat)
+	}
+	return sd
+}
+
+// MarshalJSON marshals d in LegacyFormat, unless it belongs to a Security returned by
+// Structured
 func (d Description) MarshalJSON() ([]byte, error) {
+	if d.structured {
+		return ffjson.Marshal(d.Structured())
+	}
 	var details []string
 	if len(d.Ticker) > 0 {
 		details = append(details, d.Ticker)
@@ -68,28 +148,240 @@ func (d Description) MarshalJSON() ([]byte, error) {
 	if !d.Maturity.IsZero() {
 		details = append(details, d.Maturity.Format(DescriptionDateFormat))
 	}
+	label := d.IssueTypeInfo().Label
 	if len(details) > 0 {
-		return ffjson.Marshal(d.IssueType.String() + "  " + strings.Join(details, " "))
+		return ffjson.Marshal(label + "  " + strings.Join(details, " "))
+	}
+	return ffjson.Marshal(label)
+}
+
+// UnmarshalJSON decodes d from either format.  A Description decoded from
+// StructuredFormat marshals in StructuredFormat.  The legacy string rounds the coupon
+// to two decimal places, so it only round-trips coupons given to that precision.
+func (d *Description) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '{' {
+		sd := &StructuredDescription{}
+		err := ffjson.Unmarshal(data, sd)
+		if err != nil {
+			return err
+		}
+		return d.fromStructured(sd)
+	}
+	var legacy string
+	err := ffjson.Unmarshal(data, &legacy)
+	if err != nil {
+		return err
+	}
+	return d.fromLegacy(legacy)
+}
+
+func (d *Description) fromStructured(sd *StructuredDescription) (err error) {
+	*d = Description{Ticker: sd.Ticker, Coupon: sd.Coupon, IssueCode: sd.IssueType, structured: true}
+	if len(d.IssueCode) == 0 && sd.IssueTypeLabel != NA.String() {
+		if info, ok := lookupIssueTypeLabel(sd.IssueTypeLabel); ok {
+			d.IssueCode = info.Code
+		}
+	}
+	d.IssueType = IssueTypeFromString(d.IssueCode)
+	if len(sd.Maturity) > 0 {
+		d.Maturity, err = time.Parse(FactSetDateFormat, sd.Maturity)
+	}
+	return
+}
+
+// fromLegacy parses the string written by MarshalJSON: the issue type label, then if
+// there are details two spaces followed by the ticker, coupon and maturity, each
+// optional and separated by single spaces.  A label that is not registered here, such
+// as one a server loaded with -issuetypes, is NA.
+func (d *Description) fromLegacy(legacy string) (err error) {
+	*d = Description{}
+	label, details := legacy, ""
+	if i := strings.Index(legacy, "  "); i >= 0 {
+		label, details = legacy[:i], legacy[i+2:]
+	}
+	if label != NA.String() {
+		if info, ok := lookupIssueTypeLabel(label); ok {
+			d.IssueCode = info.Code
+			d.IssueType = IssueTypeFromString(info.Code)
+		}
+	}
+	fields := strings.Split(details, " ")
+	if n := len(fields); n > 0 {
+		if m, err := time.Parse(DescriptionDateFormat, fields[n-1]); err == nil {
+			d.Maturity = m
+			fields = fields[:n-1]
+		}
+	}
+	if n := len(fields); n > 0 && strings.HasSuffix(fields[n-1], "%") {
+		d.Coupon, err = strconv.ParseFloat(strings.TrimSuffix(fields[n-1], "%"), 64)
+		if err != nil {
+			return fmt.Errorf("description %q: bad coupon: %s", legacy, err)
+		}
+		fields = fields[:n-1]
 	}
-	return ffjson.Marshal(d.IssueType.String())
+	d.Ticker = strings.Join(fields, " ")
+	return nil
 }
 
-// ffjson: nodecoder
 type Security struct {
-	LegalEntityID string      `json:"LegalEntityId,omitempty"`
-	CUSIP         string      `json:"Cusip,omitempty"`
-	ISIN          string      `json:",omitempty"`
-	SEDOL         string      `json:"Sedol,omitempty"`
-	Ticker        string      `json:",omitempty"`
-	Description   Description `json:",omitempty"`
+	LegalEntityID string `json:"LegalEntityId,omitempty"`
+	CUSIP         string `json:"Cusip,omitempty"`
+	ISIN          string `json:",omitempty"`
+	SEDOL         string `json:"Sedol,omitempty"`
+	Ticker        string `json:",omitempty"`
+	Country       string `json:",omitempty"`
+	Currency      string `json:",omitempty"`
+	CIC           CIC    `json:",omitempty"`
+	// Inception and Termination are the dates the security was issued and ceased to
+	// trade, in FactSetDateFormat; empty if unknown
+	Inception   string      `json:",omitempty"`
+	Termination string      `json:",omitempty"`
+	Description Description `json:",omitempty"`
+	// Status is the Lifecycle as of the date of the query that returned the security,
+	// or LifecycleUnknown if it was not computed
+	Status Lifecycle `json:",omitempty"`
+}
+
+// Structured returns a copy of s whose Description marshals in StructuredFormat
+func (s *Security) Structured() *Security {
+	c := *s
+	c.Description.structured = true
+	return &c
+}
+
+// SetLifecycleDates sets the Inception and Termination dates from FactSet dates, either
+// of which may be empty
+func (s *Security) SetLifecycleDates(inception, termination string) error {
+	for _, d := range []string{inception, termination} {
+		if len(d) == 0 {
+			continue
+		}
+		if _, err := time.Parse(FactSetDateFormat, d); err != nil {
+			return err
+		}
+	}
+	s.Inception, s.Termination = inception, termination
+	return nil
+}
+
+// Lifecycle returns the status of s at asOf.  A security is terminated from its
+// termination date, or the day after it matures, and pending before its inception.
+func (s *Security) Lifecycle(asOf time.Time) Lifecycle {
+	// ISO dates compare in date order as strings
+	date := asOf.Format(FactSetDateFormat)
+	switch {
+	case len(s.Termination) > 0 && s.Termination <= date:
+		return LifecycleTerminated
+	case !s.Description.Maturity.IsZero() && s.Description.Maturity.Format(FactSetDateFormat) < date:
+		return LifecycleTerminated
+	case len(s.Inception) > 0 && date < s.Inception:
+		return LifecyclePending
+	}
+	return LifecycleActive
+}
+
+// AsOf returns a copy of s whose Status is its Lifecycle at asOf
+func (s *Security) AsOf(asOf time.Time) *Security {
+	c := *s
+	c.Status = s.Lifecycle(asOf)
+	return &c
+}
+
+// SecurityFields names the fields accepted by Security.Field
+var SecurityFields = []string{"Cusip", "ISIN", "Sedol", "Ticker", "LegalEntityId", "Country",
+	"Currency", "CIC", "Inception", "Termination", "Status", "IssueType", "Description", "Coupon", "Maturity"}
+
+// Field returns the named field of s formatted as a string.  Fields are named as
+// in the JSON served by the lem server, plus IssueType, Coupon and Maturity.
+func (s *Security) Field(name string) (string, bool) {
+	switch name {
+	case "LegalEntityId":
+		return s.LegalEntityID, true
+	case "Cusip":
+		return s.CUSIP, true
+	case "ISIN":
+		return s.ISIN, true
+	case "Sedol":
+		return s.SEDOL, true
+	case "Ticker":
+		return s.Ticker, true
+	case "Country":
+		return s.Country, true
+	case "Currency":
+		return s.Currency, true
+	case "CIC":
+		return string(s.CIC), true
+	case "Inception":
+		return s.Inception, true
+	case "Termination":
+		return s.Termination, true
+	case "Status":
+		return s.Status.String(), true
+	case "Description":
+		js, err := s.Description.MarshalJSON()
+		if err != nil {
+			return "", false
+		}
+		var desc string
+		ffjson.Unmarshal(js, &desc)
+		return desc, true
+	case "IssueType":
+		return s.Description.Code(), true
+	case "Coupon":
+		if s.Description.Coupon == 0 {
+			return "", true
+		}
+		return strconv.FormatFloat(s.Description.Coupon, 'f', -1, 64), true
+	case "Maturity":
+		if s.Description.Maturity.IsZero() {
+			return "", true
+		}
+		return s.Description.Maturity.Format(FactSetDateFormat), true
+	}
+	return "", false
 }
 
-// ffjson: noencoder
 type Request struct {
 	Keys []string
+	// WithStatus requests a StatusResponse reporting the outcome of each key instead
+	// of a bare list of Securities
+	WithStatus bool `json:",omitempty"`
+	// AsOf is the date, in FactSetDateFormat, at which each Security's Status is computed;
+	// today if empty
+	AsOf string `json:",omitempty"`
+	// ExcludeTerminated reports securities terminated as of AsOf as Terminated rather
+	// than returning them
+	ExcludeTerminated bool `json:",omitempty"`
+}
+
+// KeyResult reports the outcome of looking up one key
+type KeyResult struct {
+	Key      string
+	Type     string `json:",omitempty"`
+	Status   string
+	Error    string    `json:",omitempty"`
+	Security *Security `json:",omitempty"`
+}
+
+// StatusResponse answers a Request with WithStatus set, in the order of its Keys
+type StatusResponse struct {
+	Results []*KeyResult
+	// DeniedFields are the Security fields omitted because the client is not
+	// entitled to them
+	DeniedFields []string `json:",omitempty"`
+}
+
+// SearchResponse is a page of the Securities matching a Filter, in ascending order by
+// CUSIP
+type SearchResponse struct {
+	Results []*Security
+	// Next, if set, is the CUSIP to pass as After to fetch the next page
+	Next string `json:",omitempty"`
+	// DeniedFields are the Security fields omitted because the client is not
+	// entitled to them
+	DeniedFields []string `json:",omitempty"`
 }
 
-// ffjson: nodecoder
 type Response struct {
 	Results map[string]*Security
 }
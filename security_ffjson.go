@@ -7,27 +7,101 @@ package fast_lem
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	fflib "github.com/pquerna/ffjson/fflib/v1"
 )
 
+func (mj *KeyResult) MarshalJSON() ([]byte, error) {
+	var buf fflib.Buffer
+	if mj == nil {
+		buf.WriteString("null")
+		return buf.Bytes(), nil
+	}
+	err := mj.MarshalJSONBuf(&buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+func (mj *KeyResult) MarshalJSONBuf(buf fflib.EncodingBuffer) error {
+	if mj == nil {
+		buf.WriteString("null")
+		return nil
+	}
+	var err error
+	var obj []byte
+	_ = obj
+	_ = err
+	buf.WriteString(`{ "Key":`)
+	fflib.WriteJsonString(buf, string(mj.Key))
+	buf.WriteByte(',')
+	if len(mj.Type) != 0 {
+		buf.WriteString(`"Type":`)
+		fflib.WriteJsonString(buf, string(mj.Type))
+		buf.WriteByte(',')
+	}
+	buf.WriteString(`"Status":`)
+	fflib.WriteJsonString(buf, string(mj.Status))
+	buf.WriteByte(',')
+	if len(mj.Error) != 0 {
+		buf.WriteString(`"Error":`)
+		fflib.WriteJsonString(buf, string(mj.Error))
+		buf.WriteByte(',')
+	}
+	if mj.Security != nil {
+		if true {
+			buf.WriteString(`"Security":`)
+
+			{
+
+				err = mj.Security.MarshalJSONBuf(buf)
+				if err != nil {
+					return err
+				}
+
+			}
+			buf.WriteByte(',')
+		}
+	}
+	buf.Rewind(1)
+	buf.WriteByte('}')
+	return nil
+}
+
 const (
-	ffj_t_Requestbase = iota
-	ffj_t_Requestno_such_key
+	ffj_t_KeyResultbase = iota
+	ffj_t_KeyResultno_such_key
 
-	ffj_t_Request_Keys
+	ffj_t_KeyResult_Key
+
+	ffj_t_KeyResult_Type
+
+	ffj_t_KeyResult_Status
+
+	ffj_t_KeyResult_Error
+
+	ffj_t_KeyResult_Security
 )
 
-var ffj_key_Request_Keys = []byte("Keys")
+var ffj_key_KeyResult_Key = []byte("Key")
 
-func (uj *Request) UnmarshalJSON(input []byte) error {
+var ffj_key_KeyResult_Type = []byte("Type")
+
+var ffj_key_KeyResult_Status = []byte("Status")
+
+var ffj_key_KeyResult_Error = []byte("Error")
+
+var ffj_key_KeyResult_Security = []byte("Security")
+
+func (uj *KeyResult) UnmarshalJSON(input []byte) error {
 	fs := fflib.NewFFLexer(input)
 	return uj.UnmarshalJSONFFLexer(fs, fflib.FFParse_map_start)
 }
 
-func (uj *Request) UnmarshalJSONFFLexer(fs *fflib.FFLexer, state fflib.FFParseState) error {
+func (uj *KeyResult) UnmarshalJSONFFLexer(fs *fflib.FFLexer, state fflib.FFParseState) error {
 	var err error = nil
-	currentKey := ffj_t_Requestbase
+	currentKey := ffj_t_KeyResultbase
 	_ = currentKey
 	tok := fflib.FFTok_init
 	wantedTok := fflib.FFTok_init
@@ -73,29 +147,82 @@ mainparse:
 			kn := fs.Output.Bytes()
 			if len(kn) <= 0 {
 				// "" case. hrm.
-				currentKey = ffj_t_Requestno_such_key
+				currentKey = ffj_t_KeyResultno_such_key
 				state = fflib.FFParse_want_colon
 				goto mainparse
 			} else {
 				switch kn[0] {
 
+				case 'E':
+
+					if bytes.Equal(ffj_key_KeyResult_Error, kn) {
+						currentKey = ffj_t_KeyResult_Error
+						state = fflib.FFParse_want_colon
+						goto mainparse
+					}
+
 				case 'K':
 
-					if bytes.Equal(ffj_key_Request_Keys, kn) {
-						currentKey = ffj_t_Request_Keys
+					if bytes.Equal(ffj_key_KeyResult_Key, kn) {
+						currentKey = ffj_t_KeyResult_Key
+						state = fflib.FFParse_want_colon
+						goto mainparse
+					}
+
+				case 'S':
+
+					if bytes.Equal(ffj_key_KeyResult_Status, kn) {
+						currentKey = ffj_t_KeyResult_Status
+						state = fflib.FFParse_want_colon
+						goto mainparse
+
+					} else if bytes.Equal(ffj_key_KeyResult_Security, kn) {
+						currentKey = ffj_t_KeyResult_Security
+						state = fflib.FFParse_want_colon
+						goto mainparse
+					}
+
+				case 'T':
+
+					if bytes.Equal(ffj_key_KeyResult_Type, kn) {
+						currentKey = ffj_t_KeyResult_Type
 						state = fflib.FFParse_want_colon
 						goto mainparse
 					}
 
 				}
 
-				if fflib.EqualFoldRight(ffj_key_Request_Keys, kn) {
-					currentKey = ffj_t_Request_Keys
+				if fflib.EqualFoldRight(ffj_key_KeyResult_Security, kn) {
+					currentKey = ffj_t_KeyResult_Security
 					state = fflib.FFParse_want_colon
 					goto mainparse
 				}
 
-				currentKey = ffj_t_Requestno_such_key
+				if fflib.SimpleLetterEqualFold(ffj_key_KeyResult_Error, kn) {
+					currentKey = ffj_t_KeyResult_Error
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.EqualFoldRight(ffj_key_KeyResult_Status, kn) {
+					currentKey = ffj_t_KeyResult_Status
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.SimpleLetterEqualFold(ffj_key_KeyResult_Type, kn) {
+					currentKey = ffj_t_KeyResult_Type
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.EqualFoldRight(ffj_key_KeyResult_Key, kn) {
+					currentKey = ffj_t_KeyResult_Key
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				currentKey = ffj_t_KeyResultno_such_key
 				state = fflib.FFParse_want_colon
 				goto mainparse
 			}
@@ -112,10 +239,22 @@ mainparse:
 			if tok == fflib.FFTok_left_brace || tok == fflib.FFTok_left_bracket || tok == fflib.FFTok_integer || tok == fflib.FFTok_double || tok == fflib.FFTok_string || tok == fflib.FFTok_bool || tok == fflib.FFTok_null {
 				switch currentKey {
 
-				case ffj_t_Request_Keys:
-					goto handle_Keys
+				case ffj_t_KeyResult_Key:
+					goto handle_Key
 
-				case ffj_t_Requestno_such_key:
+				case ffj_t_KeyResult_Type:
+					goto handle_Type
+
+				case ffj_t_KeyResult_Status:
+					goto handle_Status
+
+				case ffj_t_KeyResult_Error:
+					goto handle_Error
+
+				case ffj_t_KeyResult_Security:
+					goto handle_Security
+
+				case ffj_t_KeyResultno_such_key:
 					err = fs.SkipField(tok)
 					if err != nil {
 						return fs.WrapErr(err)
@@ -129,74 +268,132 @@ mainparse:
 		}
 	}
 
-handle_Keys:
+handle_Key:
 
-	/* handler: uj.Keys type=[]string kind=slice quoted=false*/
+	/* handler: uj.Key type=string kind=string quoted=false*/
 
 	{
 
 		{
-			if tok != fflib.FFTok_left_brace && tok != fflib.FFTok_null {
-				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for ", tok))
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
 			}
 		}
 
 		if tok == fflib.FFTok_null {
-			uj.Keys = nil
+
 		} else {
 
-			uj.Keys = make([]string, 0)
+			outBuf := fs.Output.Bytes()
 
-			wantVal := true
+			uj.Key = string(string(outBuf))
 
-			for {
+		}
+	}
 
-				var tmp_uj__Keys string
+	state = fflib.FFParse_after_value
+	goto mainparse
 
-				tok = fs.Scan()
-				if tok == fflib.FFTok_error {
-					goto tokerror
-				}
-				if tok == fflib.FFTok_right_brace {
-					break
-				}
+handle_Type:
 
-				if tok == fflib.FFTok_comma {
-					if wantVal == true {
-						// TODO(pquerna): this isn't an ideal error message, this handles
-						// things like [,,,] as an array value.
-						return fs.WrapErr(fmt.Errorf("wanted value token, but got token: %v", tok))
-					}
-					continue
-				} else {
-					wantVal = true
-				}
+	/* handler: uj.Type type=string kind=string quoted=false*/
 
-				/* handler: tmp_uj__Keys type=string kind=string quoted=false*/
+	{
 
-				{
+		{
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+			}
+		}
 
-					{
-						if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
-							return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
-						}
-					}
+		if tok == fflib.FFTok_null {
 
-					if tok == fflib.FFTok_null {
+		} else {
 
-					} else {
+			outBuf := fs.Output.Bytes()
 
-						outBuf := fs.Output.Bytes()
+			uj.Type = string(string(outBuf))
 
-						tmp_uj__Keys = string(string(outBuf))
+		}
+	}
 
-					}
-				}
+	state = fflib.FFParse_after_value
+	goto mainparse
 
-				uj.Keys = append(uj.Keys, tmp_uj__Keys)
-				wantVal = false
+handle_Status:
+
+	/* handler: uj.Status type=string kind=string quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			outBuf := fs.Output.Bytes()
+
+			uj.Status = string(string(outBuf))
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_Error:
+
+	/* handler: uj.Error type=string kind=string quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
 			}
 		}
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			outBuf := fs.Output.Bytes()
+
+			uj.Error = string(string(outBuf))
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_Security:
+
+	/* handler: uj.Security type=fast_lem.Security kind=struct quoted=false*/
+
+	{
+		if tok == fflib.FFTok_null {
+
+			uj.Security = nil
+
+			state = fflib.FFParse_after_value
+			goto mainparse
+		}
+
+		if uj.Security == nil {
+			uj.Security = new(Security)
+		}
+
+		err = uj.Security.UnmarshalJSONFFLexer(fs, fflib.FFParse_want_key)
+		if err != nil {
+			return err
+		}
+		state = fflib.FFParse_after_value
 	}
 
 	state = fflib.FFParse_after_value
@@ -219,38 +416,7 @@ done:
 	return nil
 }
 
-func (mj *Response) MarshalJSON() ([]byte, error) {
-	var buf fflib.Buffer
-	if mj == nil {
-		buf.WriteString("null")
-		return buf.Bytes(), nil
-	}
-	err := mj.MarshalJSONBuf(&buf)
-	if err != nil {
-		return nil, err
-	}
-	return buf.Bytes(), nil
-}
-func (mj *Response) MarshalJSONBuf(buf fflib.EncodingBuffer) error {
-	if mj == nil {
-		buf.WriteString("null")
-		return nil
-	}
-	var err error
-	var obj []byte
-	_ = obj
-	_ = err
-	buf.WriteString(`{"Results":`)
-	/* Falling back. type=map[string]*fast_lem.Security kind=map */
-	err = buf.Encode(mj.Results)
-	if err != nil {
-		return err
-	}
-	buf.WriteByte('}')
-	return nil
-}
-
-func (mj *Security) MarshalJSON() ([]byte, error) {
+func (mj *Request) MarshalJSON() ([]byte, error) {
 	var buf fflib.Buffer
 	if mj == nil {
 		buf.WriteString("null")
@@ -262,7 +428,7 @@ func (mj *Security) MarshalJSON() ([]byte, error) {
 	}
 	return buf.Bytes(), nil
 }
-func (mj *Security) MarshalJSONBuf(buf fflib.EncodingBuffer) error {
+func (mj *Request) MarshalJSONBuf(buf fflib.EncodingBuffer) error {
 	if mj == nil {
 		buf.WriteString("null")
 		return nil
@@ -271,47 +437,2703 @@ func (mj *Security) MarshalJSONBuf(buf fflib.EncodingBuffer) error {
 	var obj []byte
 	_ = obj
 	_ = err
-	buf.WriteString(`{ `)
-	if len(mj.LegalEntityID) != 0 {
-		buf.WriteString(`"LegalEntityId":`)
-		fflib.WriteJsonString(buf, string(mj.LegalEntityID))
-		buf.WriteByte(',')
+	buf.WriteString(`{ "Keys":`)
+	if mj.Keys != nil {
+		buf.WriteString(`[`)
+		for i, v := range mj.Keys {
+			if i != 0 {
+				buf.WriteString(`,`)
+			}
+			fflib.WriteJsonString(buf, string(v))
+		}
+		buf.WriteString(`]`)
+	} else {
+		buf.WriteString(`null`)
 	}
-	if len(mj.CUSIP) != 0 {
-		buf.WriteString(`"Cusip":`)
-		fflib.WriteJsonString(buf, string(mj.CUSIP))
+	buf.WriteByte(',')
+	if mj.WithStatus != false {
+		if mj.WithStatus {
+			buf.WriteString(`"WithStatus":true`)
+		} else {
+			buf.WriteString(`"WithStatus":false`)
+		}
 		buf.WriteByte(',')
 	}
-	if len(mj.ISIN) != 0 {
-		buf.WriteString(`"ISIN":`)
-		fflib.WriteJsonString(buf, string(mj.ISIN))
+	if len(mj.AsOf) != 0 {
+		buf.WriteString(`"AsOf":`)
+		fflib.WriteJsonString(buf, string(mj.AsOf))
 		buf.WriteByte(',')
 	}
-	if len(mj.SEDOL) != 0 {
-		buf.WriteString(`"Sedol":`)
-		fflib.WriteJsonString(buf, string(mj.SEDOL))
+	if mj.ExcludeTerminated != false {
+		if mj.ExcludeTerminated {
+			buf.WriteString(`"ExcludeTerminated":true`)
+		} else {
+			buf.WriteString(`"ExcludeTerminated":false`)
+		}
 		buf.WriteByte(',')
 	}
-	if len(mj.Ticker) != 0 {
+	buf.Rewind(1)
+	buf.WriteByte('}')
+	return nil
+}
+
+const (
+	ffj_t_Requestbase = iota
+	ffj_t_Requestno_such_key
+
+	ffj_t_Request_Keys
+
+	ffj_t_Request_WithStatus
+
+	ffj_t_Request_AsOf
+
+	ffj_t_Request_ExcludeTerminated
+)
+
+var ffj_key_Request_Keys = []byte("Keys")
+
+var ffj_key_Request_WithStatus = []byte("WithStatus")
+
+var ffj_key_Request_AsOf = []byte("AsOf")
+
+var ffj_key_Request_ExcludeTerminated = []byte("ExcludeTerminated")
+
+func (uj *Request) UnmarshalJSON(input []byte) error {
+	fs := fflib.NewFFLexer(input)
+	return uj.UnmarshalJSONFFLexer(fs, fflib.FFParse_map_start)
+}
+
+func (uj *Request) UnmarshalJSONFFLexer(fs *fflib.FFLexer, state fflib.FFParseState) error {
+	var err error = nil
+	currentKey := ffj_t_Requestbase
+	_ = currentKey
+	tok := fflib.FFTok_init
+	wantedTok := fflib.FFTok_init
+
+mainparse:
+	for {
+		tok = fs.Scan()
+		//	println(fmt.Sprintf("debug: tok: %v  state: %v", tok, state))
+		if tok == fflib.FFTok_error {
+			goto tokerror
+		}
+
+		switch state {
+
+		case fflib.FFParse_map_start:
+			if tok != fflib.FFTok_left_bracket {
+				wantedTok = fflib.FFTok_left_bracket
+				goto wrongtokenerror
+			}
+			state = fflib.FFParse_want_key
+			continue
+
+		case fflib.FFParse_after_value:
+			if tok == fflib.FFTok_comma {
+				state = fflib.FFParse_want_key
+			} else if tok == fflib.FFTok_right_bracket {
+				goto done
+			} else {
+				wantedTok = fflib.FFTok_comma
+				goto wrongtokenerror
+			}
+
+		case fflib.FFParse_want_key:
+			// json {} ended. goto exit. woo.
+			if tok == fflib.FFTok_right_bracket {
+				goto done
+			}
+			if tok != fflib.FFTok_string {
+				wantedTok = fflib.FFTok_string
+				goto wrongtokenerror
+			}
+
+			kn := fs.Output.Bytes()
+			if len(kn) <= 0 {
+				// "" case. hrm.
+				currentKey = ffj_t_Requestno_such_key
+				state = fflib.FFParse_want_colon
+				goto mainparse
+			} else {
+				switch kn[0] {
+
+				case 'A':
+
+					if bytes.Equal(ffj_key_Request_AsOf, kn) {
+						currentKey = ffj_t_Request_AsOf
+						state = fflib.FFParse_want_colon
+						goto mainparse
+					}
+
+				case 'E':
+
+					if bytes.Equal(ffj_key_Request_ExcludeTerminated, kn) {
+						currentKey = ffj_t_Request_ExcludeTerminated
+						state = fflib.FFParse_want_colon
+						goto mainparse
+					}
+
+				case 'K':
+
+					if bytes.Equal(ffj_key_Request_Keys, kn) {
+						currentKey = ffj_t_Request_Keys
+						state = fflib.FFParse_want_colon
+						goto mainparse
+					}
+
+				case 'W':
+
+					if bytes.Equal(ffj_key_Request_WithStatus, kn) {
+						currentKey = ffj_t_Request_WithStatus
+						state = fflib.FFParse_want_colon
+						goto mainparse
+					}
+
+				}
+
+				if fflib.SimpleLetterEqualFold(ffj_key_Request_ExcludeTerminated, kn) {
+					currentKey = ffj_t_Request_ExcludeTerminated
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.EqualFoldRight(ffj_key_Request_AsOf, kn) {
+					currentKey = ffj_t_Request_AsOf
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.EqualFoldRight(ffj_key_Request_WithStatus, kn) {
+					currentKey = ffj_t_Request_WithStatus
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.EqualFoldRight(ffj_key_Request_Keys, kn) {
+					currentKey = ffj_t_Request_Keys
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				currentKey = ffj_t_Requestno_such_key
+				state = fflib.FFParse_want_colon
+				goto mainparse
+			}
+
+		case fflib.FFParse_want_colon:
+			if tok != fflib.FFTok_colon {
+				wantedTok = fflib.FFTok_colon
+				goto wrongtokenerror
+			}
+			state = fflib.FFParse_want_value
+			continue
+		case fflib.FFParse_want_value:
+
+			if tok == fflib.FFTok_left_brace || tok == fflib.FFTok_left_bracket || tok == fflib.FFTok_integer || tok == fflib.FFTok_double || tok == fflib.FFTok_string || tok == fflib.FFTok_bool || tok == fflib.FFTok_null {
+				switch currentKey {
+
+				case ffj_t_Request_Keys:
+					goto handle_Keys
+
+				case ffj_t_Request_WithStatus:
+					goto handle_WithStatus
+
+				case ffj_t_Request_AsOf:
+					goto handle_AsOf
+
+				case ffj_t_Request_ExcludeTerminated:
+					goto handle_ExcludeTerminated
+
+				case ffj_t_Requestno_such_key:
+					err = fs.SkipField(tok)
+					if err != nil {
+						return fs.WrapErr(err)
+					}
+					state = fflib.FFParse_after_value
+					goto mainparse
+				}
+			} else {
+				goto wantedvalue
+			}
+		}
+	}
+
+handle_Keys:
+
+	/* handler: uj.Keys type=[]string kind=slice quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_left_brace && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for ", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+			uj.Keys = nil
+		} else {
+
+			uj.Keys = make([]string, 0)
+
+			wantVal := true
+
+			for {
+
+				var tmp_uj__Keys string
+
+				tok = fs.Scan()
+				if tok == fflib.FFTok_error {
+					goto tokerror
+				}
+				if tok == fflib.FFTok_right_brace {
+					break
+				}
+
+				if tok == fflib.FFTok_comma {
+					if wantVal == true {
+						// TODO(pquerna): this isn't an ideal error message, this handles
+						// things like [,,,] as an array value.
+						return fs.WrapErr(fmt.Errorf("wanted value token, but got token: %v", tok))
+					}
+					continue
+				} else {
+					wantVal = true
+				}
+
+				/* handler: tmp_uj__Keys type=string kind=string quoted=false*/
+
+				{
+
+					{
+						if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+							return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+						}
+					}
+
+					if tok == fflib.FFTok_null {
+
+					} else {
+
+						outBuf := fs.Output.Bytes()
+
+						tmp_uj__Keys = string(string(outBuf))
+
+					}
+				}
+
+				uj.Keys = append(uj.Keys, tmp_uj__Keys)
+				wantVal = false
+			}
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_WithStatus:
+
+	/* handler: uj.WithStatus type=bool kind=bool quoted=false*/
+
+	{
+		if tok != fflib.FFTok_bool && tok != fflib.FFTok_null {
+			return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for bool", tok))
+		}
+	}
+
+	{
+		if tok == fflib.FFTok_null {
+
+		} else {
+			tmpb := fs.Output.Bytes()
+
+			if bytes.Compare([]byte{'t', 'r', 'u', 'e'}, tmpb) == 0 {
+
+				uj.WithStatus = true
+
+			} else if bytes.Compare([]byte{'f', 'a', 'l', 's', 'e'}, tmpb) == 0 {
+
+				uj.WithStatus = false
+
+			} else {
+				err = errors.New("unexpected bytes for true/false value")
+				return fs.WrapErr(err)
+			}
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_AsOf:
+
+	/* handler: uj.AsOf type=string kind=string quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			outBuf := fs.Output.Bytes()
+
+			uj.AsOf = string(string(outBuf))
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_ExcludeTerminated:
+
+	/* handler: uj.ExcludeTerminated type=bool kind=bool quoted=false*/
+
+	{
+		if tok != fflib.FFTok_bool && tok != fflib.FFTok_null {
+			return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for bool", tok))
+		}
+	}
+
+	{
+		if tok == fflib.FFTok_null {
+
+		} else {
+			tmpb := fs.Output.Bytes()
+
+			if bytes.Compare([]byte{'t', 'r', 'u', 'e'}, tmpb) == 0 {
+
+				uj.ExcludeTerminated = true
+
+			} else if bytes.Compare([]byte{'f', 'a', 'l', 's', 'e'}, tmpb) == 0 {
+
+				uj.ExcludeTerminated = false
+
+			} else {
+				err = errors.New("unexpected bytes for true/false value")
+				return fs.WrapErr(err)
+			}
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+wantedvalue:
+	return fs.WrapErr(fmt.Errorf("wanted value token, but got token: %v", tok))
+wrongtokenerror:
+	return fs.WrapErr(fmt.Errorf("ffjson: wanted token: %v, but got token: %v output=%s", wantedTok, tok, fs.Output.String()))
+tokerror:
+	if fs.BigError != nil {
+		return fs.WrapErr(fs.BigError)
+	}
+	err = fs.Error.ToError()
+	if err != nil {
+		return fs.WrapErr(err)
+	}
+	panic("ffjson-generated: unreachable, please report bug.")
+done:
+	return nil
+}
+
+func (mj *Response) MarshalJSON() ([]byte, error) {
+	var buf fflib.Buffer
+	if mj == nil {
+		buf.WriteString("null")
+		return buf.Bytes(), nil
+	}
+	err := mj.MarshalJSONBuf(&buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+func (mj *Response) MarshalJSONBuf(buf fflib.EncodingBuffer) error {
+	if mj == nil {
+		buf.WriteString("null")
+		return nil
+	}
+	var err error
+	var obj []byte
+	_ = obj
+	_ = err
+	buf.WriteString(`{"Results":`)
+	/* Falling back. type=map[string]*fast_lem.Security kind=map */
+	err = buf.Encode(mj.Results)
+	if err != nil {
+		return err
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+const (
+	ffj_t_Responsebase = iota
+	ffj_t_Responseno_such_key
+
+	ffj_t_Response_Results
+)
+
+var ffj_key_Response_Results = []byte("Results")
+
+func (uj *Response) UnmarshalJSON(input []byte) error {
+	fs := fflib.NewFFLexer(input)
+	return uj.UnmarshalJSONFFLexer(fs, fflib.FFParse_map_start)
+}
+
+func (uj *Response) UnmarshalJSONFFLexer(fs *fflib.FFLexer, state fflib.FFParseState) error {
+	var err error = nil
+	currentKey := ffj_t_Responsebase
+	_ = currentKey
+	tok := fflib.FFTok_init
+	wantedTok := fflib.FFTok_init
+
+mainparse:
+	for {
+		tok = fs.Scan()
+		//	println(fmt.Sprintf("debug: tok: %v  state: %v", tok, state))
+		if tok == fflib.FFTok_error {
+			goto tokerror
+		}
+
+		switch state {
+
+		case fflib.FFParse_map_start:
+			if tok != fflib.FFTok_left_bracket {
+				wantedTok = fflib.FFTok_left_bracket
+				goto wrongtokenerror
+			}
+			state = fflib.FFParse_want_key
+			continue
+
+		case fflib.FFParse_after_value:
+			if tok == fflib.FFTok_comma {
+				state = fflib.FFParse_want_key
+			} else if tok == fflib.FFTok_right_bracket {
+				goto done
+			} else {
+				wantedTok = fflib.FFTok_comma
+				goto wrongtokenerror
+			}
+
+		case fflib.FFParse_want_key:
+			// json {} ended. goto exit. woo.
+			if tok == fflib.FFTok_right_bracket {
+				goto done
+			}
+			if tok != fflib.FFTok_string {
+				wantedTok = fflib.FFTok_string
+				goto wrongtokenerror
+			}
+
+			kn := fs.Output.Bytes()
+			if len(kn) <= 0 {
+				// "" case. hrm.
+				currentKey = ffj_t_Responseno_such_key
+				state = fflib.FFParse_want_colon
+				goto mainparse
+			} else {
+				switch kn[0] {
+
+				case 'R':
+
+					if bytes.Equal(ffj_key_Response_Results, kn) {
+						currentKey = ffj_t_Response_Results
+						state = fflib.FFParse_want_colon
+						goto mainparse
+					}
+
+				}
+
+				if fflib.EqualFoldRight(ffj_key_Response_Results, kn) {
+					currentKey = ffj_t_Response_Results
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				currentKey = ffj_t_Responseno_such_key
+				state = fflib.FFParse_want_colon
+				goto mainparse
+			}
+
+		case fflib.FFParse_want_colon:
+			if tok != fflib.FFTok_colon {
+				wantedTok = fflib.FFTok_colon
+				goto wrongtokenerror
+			}
+			state = fflib.FFParse_want_value
+			continue
+		case fflib.FFParse_want_value:
+
+			if tok == fflib.FFTok_left_brace || tok == fflib.FFTok_left_bracket || tok == fflib.FFTok_integer || tok == fflib.FFTok_double || tok == fflib.FFTok_string || tok == fflib.FFTok_bool || tok == fflib.FFTok_null {
+				switch currentKey {
+
+				case ffj_t_Response_Results:
+					goto handle_Results
+
+				case ffj_t_Responseno_such_key:
+					err = fs.SkipField(tok)
+					if err != nil {
+						return fs.WrapErr(err)
+					}
+					state = fflib.FFParse_after_value
+					goto mainparse
+				}
+			} else {
+				goto wantedvalue
+			}
+		}
+	}
+
+handle_Results:
+
+	/* handler: uj.Results type=map[string]*fast_lem.Security kind=map quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_left_bracket && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for ", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+			uj.Results = nil
+		} else {
+
+			uj.Results = make(map[string]*Security, 0)
+
+			wantVal := true
+
+			for {
+
+				var k string
+
+				var tmp_uj__Results *Security
+
+				tok = fs.Scan()
+				if tok == fflib.FFTok_error {
+					goto tokerror
+				}
+				if tok == fflib.FFTok_right_bracket {
+					break
+				}
+
+				if tok == fflib.FFTok_comma {
+					if wantVal == true {
+						// TODO(pquerna): this isn't an ideal error message, this handles
+						// things like [,,,] as an array value.
+						return fs.WrapErr(fmt.Errorf("wanted value token, but got token: %v", tok))
+					}
+					continue
+				} else {
+					wantVal = true
+				}
+
+				/* handler: k type=string kind=string quoted=false*/
+
+				{
+
+					{
+						if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+							return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+						}
+					}
+
+					if tok == fflib.FFTok_null {
+
+					} else {
+
+						outBuf := fs.Output.Bytes()
+
+						k = string(string(outBuf))
+
+					}
+				}
+
+				// Expect ':' after key
+				tok = fs.Scan()
+				if tok != fflib.FFTok_colon {
+					return fs.WrapErr(fmt.Errorf("wanted colon token, but got token: %v", tok))
+				}
+
+				tok = fs.Scan()
+				/* handler: tmp_uj__Results type=*fast_lem.Security kind=ptr quoted=false*/
+
+				{
+					if tok == fflib.FFTok_null {
+
+						tmp_uj__Results = nil
+
+						state = fflib.FFParse_after_value
+						goto mainparse
+					}
+
+					if tmp_uj__Results == nil {
+						tmp_uj__Results = new(Security)
+					}
+
+					err = tmp_uj__Results.UnmarshalJSONFFLexer(fs, fflib.FFParse_want_key)
+					if err != nil {
+						return err
+					}
+					state = fflib.FFParse_after_value
+				}
+
+				uj.Results[k] = tmp_uj__Results
+
+				wantVal = false
+			}
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+wantedvalue:
+	return fs.WrapErr(fmt.Errorf("wanted value token, but got token: %v", tok))
+wrongtokenerror:
+	return fs.WrapErr(fmt.Errorf("ffjson: wanted token: %v, but got token: %v output=%s", wantedTok, tok, fs.Output.String()))
+tokerror:
+	if fs.BigError != nil {
+		return fs.WrapErr(fs.BigError)
+	}
+	err = fs.Error.ToError()
+	if err != nil {
+		return fs.WrapErr(err)
+	}
+	panic("ffjson-generated: unreachable, please report bug.")
+done:
+	return nil
+}
+
+func (mj *SearchResponse) MarshalJSON() ([]byte, error) {
+	var buf fflib.Buffer
+	if mj == nil {
+		buf.WriteString("null")
+		return buf.Bytes(), nil
+	}
+	err := mj.MarshalJSONBuf(&buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+func (mj *SearchResponse) MarshalJSONBuf(buf fflib.EncodingBuffer) error {
+	if mj == nil {
+		buf.WriteString("null")
+		return nil
+	}
+	var err error
+	var obj []byte
+	_ = obj
+	_ = err
+	buf.WriteString(`{ "Results":`)
+	if mj.Results != nil {
+		buf.WriteString(`[`)
+		for i, v := range mj.Results {
+			if i != 0 {
+				buf.WriteString(`,`)
+			}
+
+			{
+
+				if v == nil {
+					buf.WriteString("null")
+					return nil
+				}
+
+				err = v.MarshalJSONBuf(buf)
+				if err != nil {
+					return err
+				}
+
+			}
+		}
+		buf.WriteString(`]`)
+	} else {
+		buf.WriteString(`null`)
+	}
+	buf.WriteByte(',')
+	if len(mj.Next) != 0 {
+		buf.WriteString(`"Next":`)
+		fflib.WriteJsonString(buf, string(mj.Next))
+		buf.WriteByte(',')
+	}
+	if len(mj.DeniedFields) != 0 {
+		buf.WriteString(`"DeniedFields":`)
+		if mj.DeniedFields != nil {
+			buf.WriteString(`[`)
+			for i, v := range mj.DeniedFields {
+				if i != 0 {
+					buf.WriteString(`,`)
+				}
+				fflib.WriteJsonString(buf, string(v))
+			}
+			buf.WriteString(`]`)
+		} else {
+			buf.WriteString(`null`)
+		}
+		buf.WriteByte(',')
+	}
+	buf.Rewind(1)
+	buf.WriteByte('}')
+	return nil
+}
+
+const (
+	ffj_t_SearchResponsebase = iota
+	ffj_t_SearchResponseno_such_key
+
+	ffj_t_SearchResponse_Results
+
+	ffj_t_SearchResponse_Next
+
+	ffj_t_SearchResponse_DeniedFields
+)
+
+var ffj_key_SearchResponse_Results = []byte("Results")
+
+var ffj_key_SearchResponse_Next = []byte("Next")
+
+var ffj_key_SearchResponse_DeniedFields = []byte("DeniedFields")
+
+func (uj *SearchResponse) UnmarshalJSON(input []byte) error {
+	fs := fflib.NewFFLexer(input)
+	return uj.UnmarshalJSONFFLexer(fs, fflib.FFParse_map_start)
+}
+
+func (uj *SearchResponse) UnmarshalJSONFFLexer(fs *fflib.FFLexer, state fflib.FFParseState) error {
+	var err error = nil
+	currentKey := ffj_t_SearchResponsebase
+	_ = currentKey
+	tok := fflib.FFTok_init
+	wantedTok := fflib.FFTok_init
+
+mainparse:
+	for {
+		tok = fs.Scan()
+		//	println(fmt.Sprintf("debug: tok: %v  state: %v", tok, state))
+		if tok == fflib.FFTok_error {
+			goto tokerror
+		}
+
+		switch state {
+
+		case fflib.FFParse_map_start:
+			if tok != fflib.FFTok_left_bracket {
+				wantedTok = fflib.FFTok_left_bracket
+				goto wrongtokenerror
+			}
+			state = fflib.FFParse_want_key
+			continue
+
+		case fflib.FFParse_after_value:
+			if tok == fflib.FFTok_comma {
+				state = fflib.FFParse_want_key
+			} else if tok == fflib.FFTok_right_bracket {
+				goto done
+			} else {
+				wantedTok = fflib.FFTok_comma
+				goto wrongtokenerror
+			}
+
+		case fflib.FFParse_want_key:
+			// json {} ended. goto exit. woo.
+			if tok == fflib.FFTok_right_bracket {
+				goto done
+			}
+			if tok != fflib.FFTok_string {
+				wantedTok = fflib.FFTok_string
+				goto wrongtokenerror
+			}
+
+			kn := fs.Output.Bytes()
+			if len(kn) <= 0 {
+				// "" case. hrm.
+				currentKey = ffj_t_SearchResponseno_such_key
+				state = fflib.FFParse_want_colon
+				goto mainparse
+			} else {
+				switch kn[0] {
+
+				case 'D':
+
+					if bytes.Equal(ffj_key_SearchResponse_DeniedFields, kn) {
+						currentKey = ffj_t_SearchResponse_DeniedFields
+						state = fflib.FFParse_want_colon
+						goto mainparse
+					}
+
+				case 'N':
+
+					if bytes.Equal(ffj_key_SearchResponse_Next, kn) {
+						currentKey = ffj_t_SearchResponse_Next
+						state = fflib.FFParse_want_colon
+						goto mainparse
+					}
+
+				case 'R':
+
+					if bytes.Equal(ffj_key_SearchResponse_Results, kn) {
+						currentKey = ffj_t_SearchResponse_Results
+						state = fflib.FFParse_want_colon
+						goto mainparse
+					}
+
+				}
+
+				if fflib.EqualFoldRight(ffj_key_SearchResponse_DeniedFields, kn) {
+					currentKey = ffj_t_SearchResponse_DeniedFields
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.SimpleLetterEqualFold(ffj_key_SearchResponse_Next, kn) {
+					currentKey = ffj_t_SearchResponse_Next
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.EqualFoldRight(ffj_key_SearchResponse_Results, kn) {
+					currentKey = ffj_t_SearchResponse_Results
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				currentKey = ffj_t_SearchResponseno_such_key
+				state = fflib.FFParse_want_colon
+				goto mainparse
+			}
+
+		case fflib.FFParse_want_colon:
+			if tok != fflib.FFTok_colon {
+				wantedTok = fflib.FFTok_colon
+				goto wrongtokenerror
+			}
+			state = fflib.FFParse_want_value
+			continue
+		case fflib.FFParse_want_value:
+
+			if tok == fflib.FFTok_left_brace || tok == fflib.FFTok_left_bracket || tok == fflib.FFTok_integer || tok == fflib.FFTok_double || tok == fflib.FFTok_string || tok == fflib.FFTok_bool || tok == fflib.FFTok_null {
+				switch currentKey {
+
+				case ffj_t_SearchResponse_Results:
+					goto handle_Results
+
+				case ffj_t_SearchResponse_Next:
+					goto handle_Next
+
+				case ffj_t_SearchResponse_DeniedFields:
+					goto handle_DeniedFields
+
+				case ffj_t_SearchResponseno_such_key:
+					err = fs.SkipField(tok)
+					if err != nil {
+						return fs.WrapErr(err)
+					}
+					state = fflib.FFParse_after_value
+					goto mainparse
+				}
+			} else {
+				goto wantedvalue
+			}
+		}
+	}
+
+handle_Results:
+
+	/* handler: uj.Results type=[]*fast_lem.Security kind=slice quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_left_brace && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for ", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+			uj.Results = nil
+		} else {
+
+			uj.Results = make([]*Security, 0)
+
+			wantVal := true
+
+			for {
+
+				var tmp_uj__Results *Security
+
+				tok = fs.Scan()
+				if tok == fflib.FFTok_error {
+					goto tokerror
+				}
+				if tok == fflib.FFTok_right_brace {
+					break
+				}
+
+				if tok == fflib.FFTok_comma {
+					if wantVal == true {
+						// TODO(pquerna): this isn't an ideal error message, this handles
+						// things like [,,,] as an array value.
+						return fs.WrapErr(fmt.Errorf("wanted value token, but got token: %v", tok))
+					}
+					continue
+				} else {
+					wantVal = true
+				}
+
+				/* handler: tmp_uj__Results type=*fast_lem.Security kind=ptr quoted=false*/
+
+				{
+					if tok == fflib.FFTok_null {
+
+						tmp_uj__Results = nil
+
+						state = fflib.FFParse_after_value
+						goto mainparse
+					}
+
+					if tmp_uj__Results == nil {
+						tmp_uj__Results = new(Security)
+					}
+
+					err = tmp_uj__Results.UnmarshalJSONFFLexer(fs, fflib.FFParse_want_key)
+					if err != nil {
+						return err
+					}
+					state = fflib.FFParse_after_value
+				}
+
+				uj.Results = append(uj.Results, tmp_uj__Results)
+				wantVal = false
+			}
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_Next:
+
+	/* handler: uj.Next type=string kind=string quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			outBuf := fs.Output.Bytes()
+
+			uj.Next = string(string(outBuf))
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_DeniedFields:
+
+	/* handler: uj.DeniedFields type=[]string kind=slice quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_left_brace && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for ", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+			uj.DeniedFields = nil
+		} else {
+
+			uj.DeniedFields = make([]string, 0)
+
+			wantVal := true
+
+			for {
+
+				var tmp_uj__DeniedFields string
+
+				tok = fs.Scan()
+				if tok == fflib.FFTok_error {
+					goto tokerror
+				}
+				if tok == fflib.FFTok_right_brace {
+					break
+				}
+
+				if tok == fflib.FFTok_comma {
+					if wantVal == true {
+						// TODO(pquerna): this isn't an ideal error message, this handles
+						// things like [,,,] as an array value.
+						return fs.WrapErr(fmt.Errorf("wanted value token, but got token: %v", tok))
+					}
+					continue
+				} else {
+					wantVal = true
+				}
+
+				/* handler: tmp_uj__DeniedFields type=string kind=string quoted=false*/
+
+				{
+
+					{
+						if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+							return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+						}
+					}
+
+					if tok == fflib.FFTok_null {
+
+					} else {
+
+						outBuf := fs.Output.Bytes()
+
+						tmp_uj__DeniedFields = string(string(outBuf))
+
+					}
+				}
+
+				uj.DeniedFields = append(uj.DeniedFields, tmp_uj__DeniedFields)
+				wantVal = false
+			}
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+wantedvalue:
+	return fs.WrapErr(fmt.Errorf("wanted value token, but got token: %v", tok))
+wrongtokenerror:
+	return fs.WrapErr(fmt.Errorf("ffjson: wanted token: %v, but got token: %v output=%s", wantedTok, tok, fs.Output.String()))
+tokerror:
+	if fs.BigError != nil {
+		return fs.WrapErr(fs.BigError)
+	}
+	err = fs.Error.ToError()
+	if err != nil {
+		return fs.WrapErr(err)
+	}
+	panic("ffjson-generated: unreachable, please report bug.")
+done:
+	return nil
+}
+
+func (mj *Security) MarshalJSON() ([]byte, error) {
+	var buf fflib.Buffer
+	if mj == nil {
+		buf.WriteString("null")
+		return buf.Bytes(), nil
+	}
+	err := mj.MarshalJSONBuf(&buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+func (mj *Security) MarshalJSONBuf(buf fflib.EncodingBuffer) error {
+	if mj == nil {
+		buf.WriteString("null")
+		return nil
+	}
+	var err error
+	var obj []byte
+	_ = obj
+	_ = err
+	buf.WriteString(`{ `)
+	if len(mj.LegalEntityID) != 0 {
+		buf.WriteString(`"LegalEntityId":`)
+		fflib.WriteJsonString(buf, string(mj.LegalEntityID))
+		buf.WriteByte(',')
+	}
+	if len(mj.CUSIP) != 0 {
+		buf.WriteString(`"Cusip":`)
+		fflib.WriteJsonString(buf, string(mj.CUSIP))
+		buf.WriteByte(',')
+	}
+	if len(mj.ISIN) != 0 {
+		buf.WriteString(`"ISIN":`)
+		fflib.WriteJsonString(buf, string(mj.ISIN))
+		buf.WriteByte(',')
+	}
+	if len(mj.SEDOL) != 0 {
+		buf.WriteString(`"Sedol":`)
+		fflib.WriteJsonString(buf, string(mj.SEDOL))
+		buf.WriteByte(',')
+	}
+	if len(mj.Ticker) != 0 {
+		buf.WriteString(`"Ticker":`)
+		fflib.WriteJsonString(buf, string(mj.Ticker))
+		buf.WriteByte(',')
+	}
+	if len(mj.Country) != 0 {
+		buf.WriteString(`"Country":`)
+		fflib.WriteJsonString(buf, string(mj.Country))
+		buf.WriteByte(',')
+	}
+	if len(mj.Currency) != 0 {
+		buf.WriteString(`"Currency":`)
+		fflib.WriteJsonString(buf, string(mj.Currency))
+		buf.WriteByte(',')
+	}
+	if len(mj.CIC) != 0 {
+		buf.WriteString(`"CIC":`)
+
+		{
+
+			obj, err = mj.CIC.MarshalJSON()
+			if err != nil {
+				return err
+			}
+			buf.Write(obj)
+
+		}
+		buf.WriteByte(',')
+	}
+	if len(mj.Inception) != 0 {
+		buf.WriteString(`"Inception":`)
+		fflib.WriteJsonString(buf, string(mj.Inception))
+		buf.WriteByte(',')
+	}
+	if len(mj.Termination) != 0 {
+		buf.WriteString(`"Termination":`)
+		fflib.WriteJsonString(buf, string(mj.Termination))
+		buf.WriteByte(',')
+	}
+	if true {
+		buf.WriteString(`"Description":`)
+
+		{
+
+			obj, err = mj.Description.MarshalJSON()
+			if err != nil {
+				return err
+			}
+			buf.Write(obj)
+
+		}
+		buf.WriteByte(',')
+	}
+	if mj.Status != 0 {
+		buf.WriteString(`"Status":`)
+
+		{
+
+			obj, err = mj.Status.MarshalJSON()
+			if err != nil {
+				return err
+			}
+			buf.Write(obj)
+
+		}
+		buf.WriteByte(',')
+	}
+	buf.Rewind(1)
+	buf.WriteByte('}')
+	return nil
+}
+
+const (
+	ffj_t_Securitybase = iota
+	ffj_t_Securityno_such_key
+
+	ffj_t_Security_LegalEntityID
+
+	ffj_t_Security_CUSIP
+
+	ffj_t_Security_ISIN
+
+	ffj_t_Security_SEDOL
+
+	ffj_t_Security_Ticker
+
+	ffj_t_Security_Country
+
+	ffj_t_Security_Currency
+
+	ffj_t_Security_CIC
+
+	ffj_t_Security_Inception
+
+	ffj_t_Security_Termination
+
+	ffj_t_Security_Description
+
+	ffj_t_Security_Status
+)
+
+var ffj_key_Security_LegalEntityID = []byte("LegalEntityId")
+
+var ffj_key_Security_CUSIP = []byte("Cusip")
+
+var ffj_key_Security_ISIN = []byte("ISIN")
+
+var ffj_key_Security_SEDOL = []byte("Sedol")
+
+var ffj_key_Security_Ticker = []byte("Ticker")
+
+var ffj_key_Security_Country = []byte("Country")
+
+var ffj_key_Security_Currency = []byte("Currency")
+
+var ffj_key_Security_CIC = []byte("CIC")
+
+var ffj_key_Security_Inception = []byte("Inception")
+
+var ffj_key_Security_Termination = []byte("Termination")
+
+var ffj_key_Security_Description = []byte("Description")
+
+var ffj_key_Security_Status = []byte("Status")
+
+func (uj *Security) UnmarshalJSON(input []byte) error {
+	fs := fflib.NewFFLexer(input)
+	return uj.UnmarshalJSONFFLexer(fs, fflib.FFParse_map_start)
+}
+
+func (uj *Security) UnmarshalJSONFFLexer(fs *fflib.FFLexer, state fflib.FFParseState) error {
+	var err error = nil
+	currentKey := ffj_t_Securitybase
+	_ = currentKey
+	tok := fflib.FFTok_init
+	wantedTok := fflib.FFTok_init
+
+mainparse:
+	for {
+		tok = fs.Scan()
+		//	println(fmt.Sprintf("debug: tok: %v  state: %v", tok, state))
+		if tok == fflib.FFTok_error {
+			goto tokerror
+		}
+
+		switch state {
+
+		case fflib.FFParse_map_start:
+			if tok != fflib.FFTok_left_bracket {
+				wantedTok = fflib.FFTok_left_bracket
+				goto wrongtokenerror
+			}
+			state = fflib.FFParse_want_key
+			continue
+
+		case fflib.FFParse_after_value:
+			if tok == fflib.FFTok_comma {
+				state = fflib.FFParse_want_key
+			} else if tok == fflib.FFTok_right_bracket {
+				goto done
+			} else {
+				wantedTok = fflib.FFTok_comma
+				goto wrongtokenerror
+			}
+
+		case fflib.FFParse_want_key:
+			// json {} ended. goto exit. woo.
+			if tok == fflib.FFTok_right_bracket {
+				goto done
+			}
+			if tok != fflib.FFTok_string {
+				wantedTok = fflib.FFTok_string
+				goto wrongtokenerror
+			}
+
+			kn := fs.Output.Bytes()
+			if len(kn) <= 0 {
+				// "" case. hrm.
+				currentKey = ffj_t_Securityno_such_key
+				state = fflib.FFParse_want_colon
+				goto mainparse
+			} else {
+				switch kn[0] {
+
+				case 'C':
+
+					if bytes.Equal(ffj_key_Security_CUSIP, kn) {
+						currentKey = ffj_t_Security_CUSIP
+						state = fflib.FFParse_want_colon
+						goto mainparse
+
+					} else if bytes.Equal(ffj_key_Security_Country, kn) {
+						currentKey = ffj_t_Security_Country
+						state = fflib.FFParse_want_colon
+						goto mainparse
+
+					} else if bytes.Equal(ffj_key_Security_Currency, kn) {
+						currentKey = ffj_t_Security_Currency
+						state = fflib.FFParse_want_colon
+						goto mainparse
+
+					} else if bytes.Equal(ffj_key_Security_CIC, kn) {
+						currentKey = ffj_t_Security_CIC
+						state = fflib.FFParse_want_colon
+						goto mainparse
+					}
+
+				case 'D':
+
+					if bytes.Equal(ffj_key_Security_Description, kn) {
+						currentKey = ffj_t_Security_Description
+						state = fflib.FFParse_want_colon
+						goto mainparse
+					}
+
+				case 'I':
+
+					if bytes.Equal(ffj_key_Security_ISIN, kn) {
+						currentKey = ffj_t_Security_ISIN
+						state = fflib.FFParse_want_colon
+						goto mainparse
+
+					} else if bytes.Equal(ffj_key_Security_Inception, kn) {
+						currentKey = ffj_t_Security_Inception
+						state = fflib.FFParse_want_colon
+						goto mainparse
+					}
+
+				case 'L':
+
+					if bytes.Equal(ffj_key_Security_LegalEntityID, kn) {
+						currentKey = ffj_t_Security_LegalEntityID
+						state = fflib.FFParse_want_colon
+						goto mainparse
+					}
+
+				case 'S':
+
+					if bytes.Equal(ffj_key_Security_SEDOL, kn) {
+						currentKey = ffj_t_Security_SEDOL
+						state = fflib.FFParse_want_colon
+						goto mainparse
+
+					} else if bytes.Equal(ffj_key_Security_Status, kn) {
+						currentKey = ffj_t_Security_Status
+						state = fflib.FFParse_want_colon
+						goto mainparse
+					}
+
+				case 'T':
+
+					if bytes.Equal(ffj_key_Security_Ticker, kn) {
+						currentKey = ffj_t_Security_Ticker
+						state = fflib.FFParse_want_colon
+						goto mainparse
+
+					} else if bytes.Equal(ffj_key_Security_Termination, kn) {
+						currentKey = ffj_t_Security_Termination
+						state = fflib.FFParse_want_colon
+						goto mainparse
+					}
+
+				}
+
+				if fflib.EqualFoldRight(ffj_key_Security_Status, kn) {
+					currentKey = ffj_t_Security_Status
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.EqualFoldRight(ffj_key_Security_Description, kn) {
+					currentKey = ffj_t_Security_Description
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.SimpleLetterEqualFold(ffj_key_Security_Termination, kn) {
+					currentKey = ffj_t_Security_Termination
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.SimpleLetterEqualFold(ffj_key_Security_Inception, kn) {
+					currentKey = ffj_t_Security_Inception
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.SimpleLetterEqualFold(ffj_key_Security_CIC, kn) {
+					currentKey = ffj_t_Security_CIC
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.SimpleLetterEqualFold(ffj_key_Security_Currency, kn) {
+					currentKey = ffj_t_Security_Currency
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.SimpleLetterEqualFold(ffj_key_Security_Country, kn) {
+					currentKey = ffj_t_Security_Country
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.EqualFoldRight(ffj_key_Security_Ticker, kn) {
+					currentKey = ffj_t_Security_Ticker
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.EqualFoldRight(ffj_key_Security_SEDOL, kn) {
+					currentKey = ffj_t_Security_SEDOL
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.EqualFoldRight(ffj_key_Security_ISIN, kn) {
+					currentKey = ffj_t_Security_ISIN
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.EqualFoldRight(ffj_key_Security_CUSIP, kn) {
+					currentKey = ffj_t_Security_CUSIP
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.SimpleLetterEqualFold(ffj_key_Security_LegalEntityID, kn) {
+					currentKey = ffj_t_Security_LegalEntityID
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				currentKey = ffj_t_Securityno_such_key
+				state = fflib.FFParse_want_colon
+				goto mainparse
+			}
+
+		case fflib.FFParse_want_colon:
+			if tok != fflib.FFTok_colon {
+				wantedTok = fflib.FFTok_colon
+				goto wrongtokenerror
+			}
+			state = fflib.FFParse_want_value
+			continue
+		case fflib.FFParse_want_value:
+
+			if tok == fflib.FFTok_left_brace || tok == fflib.FFTok_left_bracket || tok == fflib.FFTok_integer || tok == fflib.FFTok_double || tok == fflib.FFTok_string || tok == fflib.FFTok_bool || tok == fflib.FFTok_null {
+				switch currentKey {
+
+				case ffj_t_Security_LegalEntityID:
+					goto handle_LegalEntityID
+
+				case ffj_t_Security_CUSIP:
+					goto handle_CUSIP
+
+				case ffj_t_Security_ISIN:
+					goto handle_ISIN
+
+				case ffj_t_Security_SEDOL:
+					goto handle_SEDOL
+
+				case ffj_t_Security_Ticker:
+					goto handle_Ticker
+
+				case ffj_t_Security_Country:
+					goto handle_Country
+
+				case ffj_t_Security_Currency:
+					goto handle_Currency
+
+				case ffj_t_Security_CIC:
+					goto handle_CIC
+
+				case ffj_t_Security_Inception:
+					goto handle_Inception
+
+				case ffj_t_Security_Termination:
+					goto handle_Termination
+
+				case ffj_t_Security_Description:
+					goto handle_Description
+
+				case ffj_t_Security_Status:
+					goto handle_Status
+
+				case ffj_t_Securityno_such_key:
+					err = fs.SkipField(tok)
+					if err != nil {
+						return fs.WrapErr(err)
+					}
+					state = fflib.FFParse_after_value
+					goto mainparse
+				}
+			} else {
+				goto wantedvalue
+			}
+		}
+	}
+
+handle_LegalEntityID:
+
+	/* handler: uj.LegalEntityID type=string kind=string quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			outBuf := fs.Output.Bytes()
+
+			uj.LegalEntityID = string(string(outBuf))
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_CUSIP:
+
+	/* handler: uj.CUSIP type=string kind=string quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			outBuf := fs.Output.Bytes()
+
+			uj.CUSIP = string(string(outBuf))
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_ISIN:
+
+	/* handler: uj.ISIN type=string kind=string quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			outBuf := fs.Output.Bytes()
+
+			uj.ISIN = string(string(outBuf))
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_SEDOL:
+
+	/* handler: uj.SEDOL type=string kind=string quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			outBuf := fs.Output.Bytes()
+
+			uj.SEDOL = string(string(outBuf))
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_Ticker:
+
+	/* handler: uj.Ticker type=string kind=string quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			outBuf := fs.Output.Bytes()
+
+			uj.Ticker = string(string(outBuf))
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_Country:
+
+	/* handler: uj.Country type=string kind=string quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			outBuf := fs.Output.Bytes()
+
+			uj.Country = string(string(outBuf))
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_Currency:
+
+	/* handler: uj.Currency type=string kind=string quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			outBuf := fs.Output.Bytes()
+
+			uj.Currency = string(string(outBuf))
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_CIC:
+
+	/* handler: uj.CIC type=fast_lem.CIC kind=string quoted=false*/
+
+	{
+		if tok == fflib.FFTok_null {
+
+			state = fflib.FFParse_after_value
+			goto mainparse
+		}
+
+		tbuf, err := fs.CaptureField(tok)
+		if err != nil {
+			return fs.WrapErr(err)
+		}
+
+		err = uj.CIC.UnmarshalJSON(tbuf)
+		if err != nil {
+			return fs.WrapErr(err)
+		}
+		state = fflib.FFParse_after_value
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_Inception:
+
+	/* handler: uj.Inception type=string kind=string quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			outBuf := fs.Output.Bytes()
+
+			uj.Inception = string(string(outBuf))
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_Termination:
+
+	/* handler: uj.Termination type=string kind=string quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			outBuf := fs.Output.Bytes()
+
+			uj.Termination = string(string(outBuf))
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_Description:
+
+	/* handler: uj.Description type=fast_lem.Description kind=struct quoted=false*/
+
+	{
+		if tok == fflib.FFTok_null {
+
+			state = fflib.FFParse_after_value
+			goto mainparse
+		}
+
+		tbuf, err := fs.CaptureField(tok)
+		if err != nil {
+			return fs.WrapErr(err)
+		}
+
+		err = uj.Description.UnmarshalJSON(tbuf)
+		if err != nil {
+			return fs.WrapErr(err)
+		}
+		state = fflib.FFParse_after_value
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_Status:
+
+	/* handler: uj.Status type=fast_lem.Lifecycle kind=int quoted=false*/
+
+	{
+		if tok == fflib.FFTok_null {
+
+			state = fflib.FFParse_after_value
+			goto mainparse
+		}
+
+		tbuf, err := fs.CaptureField(tok)
+		if err != nil {
+			return fs.WrapErr(err)
+		}
+
+		err = uj.Status.UnmarshalJSON(tbuf)
+		if err != nil {
+			return fs.WrapErr(err)
+		}
+		state = fflib.FFParse_after_value
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+wantedvalue:
+	return fs.WrapErr(fmt.Errorf("wanted value token, but got token: %v", tok))
+wrongtokenerror:
+	return fs.WrapErr(fmt.Errorf("ffjson: wanted token: %v, but got token: %v output=%s", wantedTok, tok, fs.Output.String()))
+tokerror:
+	if fs.BigError != nil {
+		return fs.WrapErr(fs.BigError)
+	}
+	err = fs.Error.ToError()
+	if err != nil {
+		return fs.WrapErr(err)
+	}
+	panic("ffjson-generated: unreachable, please report bug.")
+done:
+	return nil
+}
+
+func (mj *StatusResponse) MarshalJSON() ([]byte, error) {
+	var buf fflib.Buffer
+	if mj == nil {
+		buf.WriteString("null")
+		return buf.Bytes(), nil
+	}
+	err := mj.MarshalJSONBuf(&buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+func (mj *StatusResponse) MarshalJSONBuf(buf fflib.EncodingBuffer) error {
+	if mj == nil {
+		buf.WriteString("null")
+		return nil
+	}
+	var err error
+	var obj []byte
+	_ = obj
+	_ = err
+	buf.WriteString(`{ "Results":`)
+	if mj.Results != nil {
+		buf.WriteString(`[`)
+		for i, v := range mj.Results {
+			if i != 0 {
+				buf.WriteString(`,`)
+			}
+
+			{
+
+				if v == nil {
+					buf.WriteString("null")
+					return nil
+				}
+
+				err = v.MarshalJSONBuf(buf)
+				if err != nil {
+					return err
+				}
+
+			}
+		}
+		buf.WriteString(`]`)
+	} else {
+		buf.WriteString(`null`)
+	}
+	buf.WriteByte(',')
+	if len(mj.DeniedFields) != 0 {
+		buf.WriteString(`"DeniedFields":`)
+		if mj.DeniedFields != nil {
+			buf.WriteString(`[`)
+			for i, v := range mj.DeniedFields {
+				if i != 0 {
+					buf.WriteString(`,`)
+				}
+				fflib.WriteJsonString(buf, string(v))
+			}
+			buf.WriteString(`]`)
+		} else {
+			buf.WriteString(`null`)
+		}
+		buf.WriteByte(',')
+	}
+	buf.Rewind(1)
+	buf.WriteByte('}')
+	return nil
+}
+
+const (
+	ffj_t_StatusResponsebase = iota
+	ffj_t_StatusResponseno_such_key
+
+	ffj_t_StatusResponse_Results
+
+	ffj_t_StatusResponse_DeniedFields
+)
+
+var ffj_key_StatusResponse_Results = []byte("Results")
+
+var ffj_key_StatusResponse_DeniedFields = []byte("DeniedFields")
+
+func (uj *StatusResponse) UnmarshalJSON(input []byte) error {
+	fs := fflib.NewFFLexer(input)
+	return uj.UnmarshalJSONFFLexer(fs, fflib.FFParse_map_start)
+}
+
+func (uj *StatusResponse) UnmarshalJSONFFLexer(fs *fflib.FFLexer, state fflib.FFParseState) error {
+	var err error = nil
+	currentKey := ffj_t_StatusResponsebase
+	_ = currentKey
+	tok := fflib.FFTok_init
+	wantedTok := fflib.FFTok_init
+
+mainparse:
+	for {
+		tok = fs.Scan()
+		//	println(fmt.Sprintf("debug: tok: %v  state: %v", tok, state))
+		if tok == fflib.FFTok_error {
+			goto tokerror
+		}
+
+		switch state {
+
+		case fflib.FFParse_map_start:
+			if tok != fflib.FFTok_left_bracket {
+				wantedTok = fflib.FFTok_left_bracket
+				goto wrongtokenerror
+			}
+			state = fflib.FFParse_want_key
+			continue
+
+		case fflib.FFParse_after_value:
+			if tok == fflib.FFTok_comma {
+				state = fflib.FFParse_want_key
+			} else if tok == fflib.FFTok_right_bracket {
+				goto done
+			} else {
+				wantedTok = fflib.FFTok_comma
+				goto wrongtokenerror
+			}
+
+		case fflib.FFParse_want_key:
+			// json {} ended. goto exit. woo.
+			if tok == fflib.FFTok_right_bracket {
+				goto done
+			}
+			if tok != fflib.FFTok_string {
+				wantedTok = fflib.FFTok_string
+				goto wrongtokenerror
+			}
+
+			kn := fs.Output.Bytes()
+			if len(kn) <= 0 {
+				// "" case. hrm.
+				currentKey = ffj_t_StatusResponseno_such_key
+				state = fflib.FFParse_want_colon
+				goto mainparse
+			} else {
+				switch kn[0] {
+
+				case 'D':
+
+					if bytes.Equal(ffj_key_StatusResponse_DeniedFields, kn) {
+						currentKey = ffj_t_StatusResponse_DeniedFields
+						state = fflib.FFParse_want_colon
+						goto mainparse
+					}
+
+				case 'R':
+
+					if bytes.Equal(ffj_key_StatusResponse_Results, kn) {
+						currentKey = ffj_t_StatusResponse_Results
+						state = fflib.FFParse_want_colon
+						goto mainparse
+					}
+
+				}
+
+				if fflib.EqualFoldRight(ffj_key_StatusResponse_DeniedFields, kn) {
+					currentKey = ffj_t_StatusResponse_DeniedFields
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.EqualFoldRight(ffj_key_StatusResponse_Results, kn) {
+					currentKey = ffj_t_StatusResponse_Results
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				currentKey = ffj_t_StatusResponseno_such_key
+				state = fflib.FFParse_want_colon
+				goto mainparse
+			}
+
+		case fflib.FFParse_want_colon:
+			if tok != fflib.FFTok_colon {
+				wantedTok = fflib.FFTok_colon
+				goto wrongtokenerror
+			}
+			state = fflib.FFParse_want_value
+			continue
+		case fflib.FFParse_want_value:
+
+			if tok == fflib.FFTok_left_brace || tok == fflib.FFTok_left_bracket || tok == fflib.FFTok_integer || tok == fflib.FFTok_double || tok == fflib.FFTok_string || tok == fflib.FFTok_bool || tok == fflib.FFTok_null {
+				switch currentKey {
+
+				case ffj_t_StatusResponse_Results:
+					goto handle_Results
+
+				case ffj_t_StatusResponse_DeniedFields:
+					goto handle_DeniedFields
+
+				case ffj_t_StatusResponseno_such_key:
+					err = fs.SkipField(tok)
+					if err != nil {
+						return fs.WrapErr(err)
+					}
+					state = fflib.FFParse_after_value
+					goto mainparse
+				}
+			} else {
+				goto wantedvalue
+			}
+		}
+	}
+
+handle_Results:
+
+	/* handler: uj.Results type=[]*fast_lem.KeyResult kind=slice quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_left_brace && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for ", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+			uj.Results = nil
+		} else {
+
+			uj.Results = make([]*KeyResult, 0)
+
+			wantVal := true
+
+			for {
+
+				var tmp_uj__Results *KeyResult
+
+				tok = fs.Scan()
+				if tok == fflib.FFTok_error {
+					goto tokerror
+				}
+				if tok == fflib.FFTok_right_brace {
+					break
+				}
+
+				if tok == fflib.FFTok_comma {
+					if wantVal == true {
+						// TODO(pquerna): this isn't an ideal error message, this handles
+						// things like [,,,] as an array value.
+						return fs.WrapErr(fmt.Errorf("wanted value token, but got token: %v", tok))
+					}
+					continue
+				} else {
+					wantVal = true
+				}
+
+				/* handler: tmp_uj__Results type=*fast_lem.KeyResult kind=ptr quoted=false*/
+
+				{
+					if tok == fflib.FFTok_null {
+
+						tmp_uj__Results = nil
+
+						state = fflib.FFParse_after_value
+						goto mainparse
+					}
+
+					if tmp_uj__Results == nil {
+						tmp_uj__Results = new(KeyResult)
+					}
+
+					err = tmp_uj__Results.UnmarshalJSONFFLexer(fs, fflib.FFParse_want_key)
+					if err != nil {
+						return err
+					}
+					state = fflib.FFParse_after_value
+				}
+
+				uj.Results = append(uj.Results, tmp_uj__Results)
+				wantVal = false
+			}
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_DeniedFields:
+
+	/* handler: uj.DeniedFields type=[]string kind=slice quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_left_brace && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for ", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+			uj.DeniedFields = nil
+		} else {
+
+			uj.DeniedFields = make([]string, 0)
+
+			wantVal := true
+
+			for {
+
+				var tmp_uj__DeniedFields string
+
+				tok = fs.Scan()
+				if tok == fflib.FFTok_error {
+					goto tokerror
+				}
+				if tok == fflib.FFTok_right_brace {
+					break
+				}
+
+				if tok == fflib.FFTok_comma {
+					if wantVal == true {
+						// TODO(pquerna): this isn't an ideal error message, this handles
+						// things like [,,,] as an array value.
+						return fs.WrapErr(fmt.Errorf("wanted value token, but got token: %v", tok))
+					}
+					continue
+				} else {
+					wantVal = true
+				}
+
+				/* handler: tmp_uj__DeniedFields type=string kind=string quoted=false*/
+
+				{
+
+					{
+						if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+							return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+						}
+					}
+
+					if tok == fflib.FFTok_null {
+
+					} else {
+
+						outBuf := fs.Output.Bytes()
+
+						tmp_uj__DeniedFields = string(string(outBuf))
+
+					}
+				}
+
+				uj.DeniedFields = append(uj.DeniedFields, tmp_uj__DeniedFields)
+				wantVal = false
+			}
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+wantedvalue:
+	return fs.WrapErr(fmt.Errorf("wanted value token, but got token: %v", tok))
+wrongtokenerror:
+	return fs.WrapErr(fmt.Errorf("ffjson: wanted token: %v, but got token: %v output=%s", wantedTok, tok, fs.Output.String()))
+tokerror:
+	if fs.BigError != nil {
+		return fs.WrapErr(fs.BigError)
+	}
+	err = fs.Error.ToError()
+	if err != nil {
+		return fs.WrapErr(err)
+	}
+	panic("ffjson-generated: unreachable, please report bug.")
+done:
+	return nil
+}
+
+func (mj *StructuredDescription) MarshalJSON() ([]byte, error) {
+	var buf fflib.Buffer
+	if mj == nil {
+		buf.WriteString("null")
+		return buf.Bytes(), nil
+	}
+	err := mj.MarshalJSONBuf(&buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+func (mj *StructuredDescription) MarshalJSONBuf(buf fflib.EncodingBuffer) error {
+	if mj == nil {
+		buf.WriteString("null")
+		return nil
+	}
+	var err error
+	var obj []byte
+	_ = obj
+	_ = err
+	buf.WriteString(`{ `)
+	if len(mj.IssueType) != 0 {
+		buf.WriteString(`"IssueType":`)
+		fflib.WriteJsonString(buf, string(mj.IssueType))
+		buf.WriteByte(',')
+	}
+	buf.WriteString(`"IssueTypeLabel":`)
+	fflib.WriteJsonString(buf, string(mj.IssueTypeLabel))
+	buf.WriteByte(',')
+	if len(mj.AssetClass) != 0 {
+		buf.WriteString(`"AssetClass":`)
+		fflib.WriteJsonString(buf, string(mj.AssetClass))
+		buf.WriteByte(',')
+	}
+	if len(mj.Ticker) != 0 {
 		buf.WriteString(`"Ticker":`)
 		fflib.WriteJsonString(buf, string(mj.Ticker))
 		buf.WriteByte(',')
 	}
-	if true {
-		buf.WriteString(`"Description":`)
+	if mj.Coupon != 0 {
+		buf.WriteString(`"Coupon":`)
+		fflib.AppendFloat(buf, float64(mj.Coupon), 'g', -1, 64)
+		buf.WriteByte(',')
+	}
+	if len(mj.Maturity) != 0 {
+		buf.WriteString(`"Maturity":`)
+		fflib.WriteJsonString(buf, string(mj.Maturity))
+		buf.WriteByte(',')
+	}
+	buf.Rewind(1)
+	buf.WriteByte('}')
+	return nil
+}
+
+const (
+	ffj_t_StructuredDescriptionbase = iota
+	ffj_t_StructuredDescriptionno_such_key
+
+	ffj_t_StructuredDescription_IssueType
+
+	ffj_t_StructuredDescription_IssueTypeLabel
+
+	ffj_t_StructuredDescription_AssetClass
+
+	ffj_t_StructuredDescription_Ticker
+
+	ffj_t_StructuredDescription_Coupon
+
+	ffj_t_StructuredDescription_Maturity
+)
+
+var ffj_key_StructuredDescription_IssueType = []byte("IssueType")
+
+var ffj_key_StructuredDescription_IssueTypeLabel = []byte("IssueTypeLabel")
+
+var ffj_key_StructuredDescription_AssetClass = []byte("AssetClass")
+
+var ffj_key_StructuredDescription_Ticker = []byte("Ticker")
+
+var ffj_key_StructuredDescription_Coupon = []byte("Coupon")
+
+var ffj_key_StructuredDescription_Maturity = []byte("Maturity")
+
+func (uj *StructuredDescription) UnmarshalJSON(input []byte) error {
+	fs := fflib.NewFFLexer(input)
+	return uj.UnmarshalJSONFFLexer(fs, fflib.FFParse_map_start)
+}
+
+func (uj *StructuredDescription) UnmarshalJSONFFLexer(fs *fflib.FFLexer, state fflib.FFParseState) error {
+	var err error = nil
+	currentKey := ffj_t_StructuredDescriptionbase
+	_ = currentKey
+	tok := fflib.FFTok_init
+	wantedTok := fflib.FFTok_init
+
+mainparse:
+	for {
+		tok = fs.Scan()
+		//	println(fmt.Sprintf("debug: tok: %v  state: %v", tok, state))
+		if tok == fflib.FFTok_error {
+			goto tokerror
+		}
+
+		switch state {
+
+		case fflib.FFParse_map_start:
+			if tok != fflib.FFTok_left_bracket {
+				wantedTok = fflib.FFTok_left_bracket
+				goto wrongtokenerror
+			}
+			state = fflib.FFParse_want_key
+			continue
+
+		case fflib.FFParse_after_value:
+			if tok == fflib.FFTok_comma {
+				state = fflib.FFParse_want_key
+			} else if tok == fflib.FFTok_right_bracket {
+				goto done
+			} else {
+				wantedTok = fflib.FFTok_comma
+				goto wrongtokenerror
+			}
+
+		case fflib.FFParse_want_key:
+			// json {} ended. goto exit. woo.
+			if tok == fflib.FFTok_right_bracket {
+				goto done
+			}
+			if tok != fflib.FFTok_string {
+				wantedTok = fflib.FFTok_string
+				goto wrongtokenerror
+			}
+
+			kn := fs.Output.Bytes()
+			if len(kn) <= 0 {
+				// "" case. hrm.
+				currentKey = ffj_t_StructuredDescriptionno_such_key
+				state = fflib.FFParse_want_colon
+				goto mainparse
+			} else {
+				switch kn[0] {
+
+				case 'A':
+
+					if bytes.Equal(ffj_key_StructuredDescription_AssetClass, kn) {
+						currentKey = ffj_t_StructuredDescription_AssetClass
+						state = fflib.FFParse_want_colon
+						goto mainparse
+					}
+
+				case 'C':
+
+					if bytes.Equal(ffj_key_StructuredDescription_Coupon, kn) {
+						currentKey = ffj_t_StructuredDescription_Coupon
+						state = fflib.FFParse_want_colon
+						goto mainparse
+					}
+
+				case 'I':
+
+					if bytes.Equal(ffj_key_StructuredDescription_IssueType, kn) {
+						currentKey = ffj_t_StructuredDescription_IssueType
+						state = fflib.FFParse_want_colon
+						goto mainparse
+
+					} else if bytes.Equal(ffj_key_StructuredDescription_IssueTypeLabel, kn) {
+						currentKey = ffj_t_StructuredDescription_IssueTypeLabel
+						state = fflib.FFParse_want_colon
+						goto mainparse
+					}
+
+				case 'M':
+
+					if bytes.Equal(ffj_key_StructuredDescription_Maturity, kn) {
+						currentKey = ffj_t_StructuredDescription_Maturity
+						state = fflib.FFParse_want_colon
+						goto mainparse
+					}
+
+				case 'T':
+
+					if bytes.Equal(ffj_key_StructuredDescription_Ticker, kn) {
+						currentKey = ffj_t_StructuredDescription_Ticker
+						state = fflib.FFParse_want_colon
+						goto mainparse
+					}
+
+				}
+
+				if fflib.SimpleLetterEqualFold(ffj_key_StructuredDescription_Maturity, kn) {
+					currentKey = ffj_t_StructuredDescription_Maturity
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.SimpleLetterEqualFold(ffj_key_StructuredDescription_Coupon, kn) {
+					currentKey = ffj_t_StructuredDescription_Coupon
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.EqualFoldRight(ffj_key_StructuredDescription_Ticker, kn) {
+					currentKey = ffj_t_StructuredDescription_Ticker
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.EqualFoldRight(ffj_key_StructuredDescription_AssetClass, kn) {
+					currentKey = ffj_t_StructuredDescription_AssetClass
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.EqualFoldRight(ffj_key_StructuredDescription_IssueTypeLabel, kn) {
+					currentKey = ffj_t_StructuredDescription_IssueTypeLabel
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.EqualFoldRight(ffj_key_StructuredDescription_IssueType, kn) {
+					currentKey = ffj_t_StructuredDescription_IssueType
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				currentKey = ffj_t_StructuredDescriptionno_such_key
+				state = fflib.FFParse_want_colon
+				goto mainparse
+			}
+
+		case fflib.FFParse_want_colon:
+			if tok != fflib.FFTok_colon {
+				wantedTok = fflib.FFTok_colon
+				goto wrongtokenerror
+			}
+			state = fflib.FFParse_want_value
+			continue
+		case fflib.FFParse_want_value:
+
+			if tok == fflib.FFTok_left_brace || tok == fflib.FFTok_left_bracket || tok == fflib.FFTok_integer || tok == fflib.FFTok_double || tok == fflib.FFTok_string || tok == fflib.FFTok_bool || tok == fflib.FFTok_null {
+				switch currentKey {
+
+				case ffj_t_StructuredDescription_IssueType:
+					goto handle_IssueType
+
+				case ffj_t_StructuredDescription_IssueTypeLabel:
+					goto handle_IssueTypeLabel
+
+				case ffj_t_StructuredDescription_AssetClass:
+					goto handle_AssetClass
+
+				case ffj_t_StructuredDescription_Ticker:
+					goto handle_Ticker
+
+				case ffj_t_StructuredDescription_Coupon:
+					goto handle_Coupon
+
+				case ffj_t_StructuredDescription_Maturity:
+					goto handle_Maturity
+
+				case ffj_t_StructuredDescriptionno_such_key:
+					err = fs.SkipField(tok)
+					if err != nil {
+						return fs.WrapErr(err)
+					}
+					state = fflib.FFParse_after_value
+					goto mainparse
+				}
+			} else {
+				goto wantedvalue
+			}
+		}
+	}
+
+handle_IssueType:
+
+	/* handler: uj.IssueType type=string kind=string quoted=false*/
+
+	{
 
 		{
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			outBuf := fs.Output.Bytes()
+
+			uj.IssueType = string(string(outBuf))
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_IssueTypeLabel:
+
+	/* handler: uj.IssueTypeLabel type=string kind=string quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			outBuf := fs.Output.Bytes()
+
+			uj.IssueTypeLabel = string(string(outBuf))
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_AssetClass:
+
+	/* handler: uj.AssetClass type=fast_lem.AssetClass kind=string quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for AssetClass", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			outBuf := fs.Output.Bytes()
+
+			uj.AssetClass = AssetClass(string(outBuf))
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_Ticker:
+
+	/* handler: uj.Ticker type=string kind=string quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			outBuf := fs.Output.Bytes()
+
+			uj.Ticker = string(string(outBuf))
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_Coupon:
+
+	/* handler: uj.Coupon type=float64 kind=float64 quoted=false*/
+
+	{
+		if tok != fflib.FFTok_double && tok != fflib.FFTok_integer && tok != fflib.FFTok_null {
+			return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for float64", tok))
+		}
+	}
+
+	{
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			tval, err := fflib.ParseFloat(fs.Output.Bytes(), 64)
 
-			obj, err = mj.Description.MarshalJSON()
 			if err != nil {
-				return err
+				return fs.WrapErr(err)
 			}
-			buf.Write(obj)
+
+			uj.Coupon = float64(tval)
 
 		}
-		buf.WriteByte(',')
 	}
-	buf.Rewind(1)
-	buf.WriteByte('}')
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_Maturity:
+
+	/* handler: uj.Maturity type=string kind=string quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			outBuf := fs.Output.Bytes()
+
+			uj.Maturity = string(string(outBuf))
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+wantedvalue:
+	return fs.WrapErr(fmt.Errorf("wanted value token, but got token: %v", tok))
+wrongtokenerror:
+	return fs.WrapErr(fmt.Errorf("ffjson: wanted token: %v, but got token: %v output=%s", wantedTok, tok, fs.Output.String()))
+tokerror:
+	if fs.BigError != nil {
+		return fs.WrapErr(fs.BigError)
+	}
+	err = fs.Error.ToError()
+	if err != nil {
+		return fs.WrapErr(err)
+	}
+	panic("ffjson-generated: unreachable, please report bug.")
+done:
 	return nil
 }
@@ -0,0 +1,126 @@
+package fast_lem
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/pquerna/ffjson/ffjson"
+)
+
+func TestSecurityRoundTrip(t *testing.T) {
+	securities := append(testSecurities(),
+		New("459200AS0", "US459200AS03", "", "IBM A", "000XT9-E", "BD", "5.25", "2031-11-30"),
+		&Security{CUSIP: "000000000"},
+	)
+	for _, s := range securities {
+		for _, want := range []*Security{s, s.Structured()} {
+			js, err := ffjson.Marshal(want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := &Security{}
+			err = ffjson.Unmarshal(js, got)
+			if err != nil {
+				t.Errorf("%s: %s", js, err)
+				continue
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: got %+v, want %+v", js, got, want)
+			}
+		}
+	}
+}
+
+func TestStatusResponseRoundTrip(t *testing.T) {
+	s := testSecurities()
+	want := &StatusResponse{
+		Results: []*KeyResult{
+			{Key: s[0].CUSIP, Type: "CUSIP", Status: "found", Security: s[0]},
+			{Key: "NOPE00000", Type: "CUSIP", Status: "not_found"},
+		},
+		DeniedFields: []string{"Ticker"},
+	}
+	js, err := ffjson.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := &StatusResponse{}
+	if err = ffjson.Unmarshal(js, got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %+v, want %+v", got, want)
+	}
+}
+
+func TestParseIssueType(t *testing.T) {
+	for s, want := range map[string]IssueType{"BD": BD, "bd": BD, "Bond": BD, "BOND": BD, "N/A": NA, "": NA, "ADR/GDR": AD} {
+		got, err := ParseIssueType(s)
+		if err != nil || got != want {
+			t.Errorf("%q: got %s, %v, want %s", s, got, err, want)
+		}
+	}
+	if _, err := ParseIssueType("Bondish"); err == nil {
+		t.Error("Parsed an unknown issue type")
+	}
+	var it IssueType
+	if err := ffjson.Unmarshal([]byte(`"Loan"`), &it); err != nil || it != LN {
+		t.Errorf("Got %s, %v decoding a label, want %s", it, err, LN)
+	}
+}
+
+func TestUnknownIssueType(t *testing.T) {
+	s := New("123456789", "", "", "", "", "QX", "", "")
+	if s.Description.IssueType != NA || s.Description.Code() != "QX" {
+		t.Errorf("Got %s, %q, want NA, QX", s.Description.IssueType, s.Description.Code())
+	}
+	js, err := ffjson.Marshal(s.Structured())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := &Security{}
+	if err = ffjson.Unmarshal(js, got); err != nil || got.Description.Code() != "QX" {
+		t.Errorf("%s: got %q, %v, want QX", js, got.Description.Code(), err)
+	}
+	legacy, err := ffjson.Marshal(New("123456789", "", "", "", "", "BD", "5", "2030-01-01"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy = bytes.Replace(legacy, []byte(`"Bond  `), []byte(`"Catastrophe Bond  `), 1)
+	got = &Security{}
+	if err = ffjson.Unmarshal(legacy, got); err != nil || got.Description.IssueType != NA || got.Description.Coupon != 5 ||
+		got.Description.Maturity.Format(FactSetDateFormat) != "2030-01-01" {
+		t.Errorf("%s: got %+v, %v, want an NA 5%% bond maturing 2030-01-01", legacy, got.Description, err)
+	}
+	if _, err = ffjson.Marshal(got); err != nil {
+		t.Errorf("Re-encoding an unregistered label: %s", err)
+	}
+	if err = RegisterIssueType(IssueTypeInfo{Code: "BD", Label: "Bond Again"}); err == nil {
+		t.Error("Redefined a built-in issue type")
+	}
+	if err = RegisterIssueType(IssueTypeInfo{Code: "QY", Label: "bond"}); err == nil {
+		t.Error("Registered a duplicate label")
+	}
+	err = RegisterIssueType(IssueTypeInfo{Code: "QX", Label: "Quux", AssetClass: AssetClassDerivative})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info := s.Description.IssueTypeInfo(); info.Label != "Quux" || info.AssetClass != AssetClassDerivative {
+		t.Errorf("Got %+v, want Quux, Derivative", info)
+	}
+	if got, _ := s.Field("Description"); got != "Quux" {
+		t.Errorf("Got description %q, want Quux", got)
+	}
+	w := httptest.NewRecorder()
+	IssueTypesHandler(w, httptest.NewRequest("GET", "/issuetypes?assetclass=derivative", nil))
+	want := `[{"Code":"DR","Label":"Derivative","AssetClass":"Derivative"},` +
+		`{"Code":"FU","Label":"Future Agreement","AssetClass":"Derivative"},` +
+		`{"Code":"OP","Label":"Stock Option","AssetClass":"Derivative"},` +
+		`{"Code":"QX","Label":"Quux","AssetClass":"Derivative"},` +
+		`{"Code":"WT","Label":"Warrant/Right","AssetClass":"Derivative"}]`
+	if w.Body.String() != want {
+		t.Errorf("Got %s, want %s", w.Body, want)
+	}
+}
@@ -0,0 +1,124 @@
+package fast_lem
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/golang/snappy"
+)
+
+// snapshotVersion identifies the layout written by WriteSnapshot
+const snapshotVersion = 1
+
+var (
+	ErrNotSnapshot = errors.New("not a security master snapshot")
+)
+
+// snapshotHeader precedes the securities in a snapshot stream
+type snapshotHeader struct {
+	Magic    string
+	Version  int
+	Count    int
+	Metadata *Metadata
+	// Entities is the corporate structure, if known; snapshots written before it was
+	// added decode without one
+	Entities []*Entity
+}
+
+const snapshotMagic = `fast_lem snapshot`
+
+// WriteSnapshot serializes the master to w as a snappy-compressed gob stream that
+// ReadSnapshot can load without re-sorting
+func (m *SecurityMaster) WriteSnapshot(w io.Writer) (err error) {
+	sw := snappy.NewBufferedWriter(w)
+	enc := gob.NewEncoder(sw)
+	h := &snapshotHeader{
+		Magic:    snapshotMagic,
+		Version:  snapshotVersion,
+		Count:    len(m.Securities),
+		Metadata: m.Metadata,
+	}
+	if m.Hierarchy != nil {
+		h.Entities = m.Hierarchy.Entities()
+	}
+	err = enc.Encode(h)
+	if err != nil {
+		return
+	}
+	for _, s := range m.Securities {
+		err = enc.Encode(s)
+		if err != nil {
+			return
+		}
+	}
+	return sw.Close()
+}
+
+// SaveSnapshot writes the master to a snapshot file at path
+func (m *SecurityMaster) SaveSnapshot(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = m.WriteSnapshot(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadSnapshot returns the security master serialized by WriteSnapshot
+func ReadSnapshot(r io.Reader) (m *SecurityMaster, err error) {
+	dec := gob.NewDecoder(snappy.NewReader(r))
+	h := &snapshotHeader{}
+	err = dec.Decode(h)
+	if err != nil || h.Magic != snapshotMagic {
+		return nil, ErrNotSnapshot
+	}
+	if h.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", h.Version)
+	}
+	c := make(chan *Security, 10000)
+	done := make(chan error, 1)
+	go func() {
+		defer close(c)
+		for i := 0; i < h.Count; i++ {
+			s := &Security{}
+			if err := dec.Decode(s); err != nil {
+				done <- fmt.Errorf("snapshot record %d: %s", i, err)
+				return
+			}
+			c <- s
+		}
+		done <- nil
+	}()
+	m, err = NewSecurityMaster(c)
+	for range c {
+		// drain the channel if NewSecurityMaster gave up early
+	}
+	if decErr := <-done; err == nil {
+		err = decErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	m.Metadata = h.Metadata
+	if h.Entities != nil {
+		m.Hierarchy = NewHierarchy(h.Entities)
+	}
+	return
+}
+
+// LoadSnapshot returns the security master saved at path by SaveSnapshot
+func LoadSnapshot(path string) (*SecurityMaster, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadSnapshot(f)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/nycmonkey/fast_lem"
+)
+
+var (
+	dbfile string
+	output string
+	format string
+)
+
+func init() {
+	flag.StringVar(&dbfile, "dbfile", "../db/lem.db", "path to the boltdb database to snapshot")
+	flag.StringVar(&output, "output", "../db/lem.snapshot", "path of the snapshot file to write")
+	flag.StringVar(&format, "format", "gob",
+		"snapshot format: gob (loaded into a SecurityMaster) or mapped (memory mapped by a MappedMaster)")
+	flag.Parse()
+}
+
+func main() {
+	start := time.Now()
+	var db *bolt.DB
+	var err error
+	db, err = bolt.Open(dbfile, 0666, &bolt.Options{Timeout: 1 * time.Second, ReadOnly: true})
+	if err != nil {
+		log.Fatalln("Error opening db:", err)
+	}
+	defer db.Close()
+	switch format {
+	case "gob":
+		err = writeGob(db)
+	case "mapped":
+		err = writeMapped(db)
+	default:
+		log.Fatalln("Unknown format", format)
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+	fmt.Println("Wrote", output, "in", time.Now().Sub(start))
+}
+
+func writeGob(db *bolt.DB) error {
+	m, err := fast_lem.NewSecurityMasterFromBolt(db)
+	if err != nil {
+		return err
+	}
+	return m.SaveSnapshot(output)
+}
+
+func writeMapped(db *bolt.DB) error {
+	meta, err := fast_lem.ReadMetadata(db)
+	if err != nil && err != fast_lem.ErrNoMetadata {
+		return err
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	c := make(chan *fast_lem.Security, 10000)
+	done := make(chan error, 1)
+	go func() {
+		done <- fast_lem.WriteMappedSnapshot(f, c, meta)
+	}()
+	err = fast_lem.ForEachSecurity(db, func(s *fast_lem.Security) error {
+		c <- s
+		return nil
+	})
+	close(c)
+	if writeErr := <-done; err == nil {
+		err = writeErr
+	}
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,59 @@
+package fast_lem
+
+import (
+	"bytes"
+	"testing"
+)
+
+func testSecurities() []*Security {
+	return []*Security{
+		New("851500000", "US8515000006", "", "", "8C7QCS-E", "MU", "5", "2007-07-01"),
+		New("FDS010000", "USFDS0100006", "B0YBKJ7", "", "000XT9-E", "LN", "", "2010-07-21"),
+		New("FDS020000", "", "B0YBKL9", "XYZ", "000XT9-E", "EQ", "", ""),
+	}
+}
+
+func testMaster(t testing.TB) *SecurityMaster {
+	c := make(chan *Security, 10)
+	for _, s := range testSecurities() {
+		c <- s
+	}
+	close(c)
+	m, err := NewSecurityMaster(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	m := testMaster(t)
+	m.Metadata = &Metadata{Rows: 3}
+	buf := new(bytes.Buffer)
+	err := m.WriteSnapshot(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded *SecurityMaster
+	loaded, err = ReadSnapshot(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Metadata == nil || loaded.Metadata.Rows != 3 {
+		t.Errorf("Got metadata %+v, want Rows 3", loaded.Metadata)
+	}
+	response, err := loaded.Get("FDS010000", "USFDS0100006", "B0YBKL9", "NOPE00000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"FDS010000", "FDS010000", "FDS020000", ""}
+	for i, s := range response {
+		if s.CUSIP != want[i] {
+			t.Errorf("Key %d: got CUSIP '%s', want '%s'", i, s.CUSIP, want[i])
+		}
+	}
+	_, err = ReadSnapshot(bytes.NewBufferString("not a snapshot"))
+	if err != ErrNotSnapshot {
+		t.Errorf("Got error %v, want %v", err, ErrNotSnapshot)
+	}
+}
@@ -2,10 +2,17 @@ package fast_lem
 
 import (
 	"bytes"
+	"context"
 	"encoding/gob"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/boltdb/bolt"
 	"github.com/golang/snappy"
@@ -17,6 +24,23 @@ type Getter interface {
 	Get(keys ...string) ([]*Security, error)
 }
 
+// ContextGetter looks up details of Securities by ID, giving up when ctx is done
+type ContextGetter interface {
+	GetContext(ctx context.Context, keys ...string) ([]*Security, error)
+}
+
+// GetContext looks up keys with g, which is cancelled along with ctx if g is a
+// ContextGetter and otherwise only checks ctx before starting
+func GetContext(ctx context.Context, g Getter, keys ...string) ([]*Security, error) {
+	if cg, ok := g.(ContextGetter); ok {
+		return cg.GetContext(ctx, keys...)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return g.Get(keys...)
+}
+
 // Storer persits Security details
 type Storer interface {
 	Store(chan *Security)
@@ -29,13 +53,30 @@ type Storage interface {
 }
 
 type boltPersistance struct {
-	db *bolt.DB
+	db      *bolt.DB
+	metrics *Metrics
 }
 
 func NewGetter(db *bolt.DB) Getter {
 	return &boltPersistance{db: db}
 }
 
+// NewInstrumentedGetter returns a Getter for db that records the duration of its read
+// transactions in m
+func NewInstrumentedGetter(db *bolt.DB, m *Metrics) Getter {
+	return &boltPersistance{db: db, metrics: m}
+}
+
+// view runs fn in a read transaction, timing it if metrics are enabled
+func (bp *boltPersistance) view(fn func(*bolt.Tx) error) error {
+	if bp.metrics == nil {
+		return bp.db.View(fn)
+	}
+	start := time.Now()
+	defer func() { bp.metrics.ObserveReadTx(time.Now().Sub(start)) }()
+	return bp.db.View(fn)
+}
+
 // NewStorage returns a Security database ready to use
 func NewStorage(db *bolt.DB) (Storage, error) {
 	err := db.Update(func(tx *bolt.Tx) error {
@@ -51,6 +92,16 @@ func NewStorage(db *bolt.DB) (Storage, error) {
 		if err != nil {
 			return fmt.Errorf("create bucket: %s", err)
 		}
+		_, err = tx.CreateBucketIfNotExists([]byte(MetadataBucket))
+		if err != nil {
+			return fmt.Errorf("create bucket: %s", err)
+		}
+		for _, index := range indexBuckets {
+			_, err = tx.CreateBucketIfNotExists([]byte(index.name))
+			if err != nil {
+				return fmt.Errorf("create bucket: %s", err)
+			}
+		}
 		return nil
 	})
 	return &boltPersistance{db: db}, err
@@ -73,6 +124,24 @@ func encodeSecurity(s *Security) []byte {
 	return snappy.Encode(nil, buf.Bytes())
 }
 
+// ForEachSecurity calls fn for every Security in db, in ascending order by CUSIP,
+// stopping at the first error
+func ForEachSecurity(db *bolt.DB, fn func(*Security) error) error {
+	return db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(DetailsBucket))
+		if b == nil {
+			return fmt.Errorf("bucket %s not found", DetailsBucket)
+		}
+		return b.ForEach(func(k, v []byte) error {
+			s, err := decodeSecurity(v)
+			if err != nil {
+				return fmt.Errorf("decode %s: %s", k, err)
+			}
+			return fn(s)
+		})
+	})
+}
+
 // Store persists a batch of Securities
 func (bp *boltPersistance) Store(c chan *Security) {
 	batchSize := 10000
@@ -121,6 +190,16 @@ func (bp *boltPersistance) storeBatch(batch []*Security) {
 					return err
 				}
 			}
+			for _, index := range indexBuckets {
+				value := index.value(sec)
+				if len(value) == 0 {
+					continue
+				}
+				err = tx.Bucket([]byte(index.name)).Put(indexKey(value, sec.CUSIP), []byte{})
+				if err != nil {
+					return err
+				}
+			}
 		}
 		return nil
 	})
@@ -132,60 +211,126 @@ func (bp *boltPersistance) storeBatch(batch []*Security) {
 
 // Get "hydrates" security details from one or more identifiers
 func (bp *boltPersistance) Get(keys ...string) (response []*Security, err error) {
-	size := len(keys)
-	response = make([]*Security, size)
-	i := 0
-	for _, k := range keys {
-		var s *Security
-		s, err = bp.get(k)
-		if err != nil {
-			return
+	return bp.GetContext(context.Background(), keys...)
+}
+
+// GetContext "hydrates" security details from one or more identifiers within a single
+// read transaction, giving up if ctx is done first
+func (bp *boltPersistance) GetContext(ctx context.Context, keys ...string) (response []*Security, err error) {
+	response = make([]*Security, len(keys))
+	err = bp.view(func(tx *bolt.Tx) error {
+		for i, k := range keys {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+			s, err := bp.get(tx, k)
+			if err != nil {
+				return err
+			}
+			response[i] = s
 		}
-		response[i] = s
-		i++
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return
 }
 
-func (bp *boltPersistance) get(key string) (s *Security, err error) {
-	err = bp.db.View(func(tx *bolt.Tx) error {
-		detailsBucket := tx.Bucket([]byte(DetailsBucket))
-		var cusip []byte
-		switch len(key) {
-		case 12:
-			isinBucket := tx.Bucket([]byte(IsinBucket))
-			cusip = isinBucket.Get([]byte(key))
-		case 7:
-			sedolBucket := tx.Bucket([]byte(SedolBucket))
-			cusip = sedolBucket.Get([]byte(key))
-		default:
-			cusip = []byte(key)
-		}
-		if cusip == nil {
-			s = &Security{}
-			return nil
-		}
-		encoded := detailsBucket.Get(cusip)
-		if encoded == nil {
-			s = &Security{}
+// Lookup resolves each key independently within a single read transaction
+func (bp *boltPersistance) Lookup(ctx context.Context, keys ...string) (results []Result, err error) {
+	err = bp.view(func(tx *bolt.Tx) error {
+		var err error
+		results, err = lookupEach(ctx, keys, func(key string) (*Security, error) {
+			return bp.get(tx, key)
+		})
+		return err
+	})
+	return
+}
+
+func (bp *boltPersistance) get(tx *bolt.Tx, key string) (s *Security, err error) {
+	detailsBucket := tx.Bucket([]byte(DetailsBucket))
+	var cusip []byte
+	switch len(key) {
+	case 12:
+		isinBucket := tx.Bucket([]byte(IsinBucket))
+		cusip = isinBucket.Get([]byte(key))
+	case 7:
+		sedolBucket := tx.Bucket([]byte(SedolBucket))
+		cusip = sedolBucket.Get([]byte(key))
+	default:
+		cusip = []byte(key)
+	}
+	if cusip == nil {
+		return &Security{}, nil
+	}
+	encoded := detailsBucket.Get(cusip)
+	if encoded == nil {
+		return &Security{}, nil
+	}
+	return decodeSecurity(encoded)
+}
+
+// Describe returns the provenance of the data in the database
+func (bp *boltPersistance) Describe() (*Metadata, error) {
+	return ReadMetadata(bp.db)
+}
+
+// RecordCounts returns the number of keys in each top-level bucket.  Bolt counts keys
+// by walking the bucket, so this reads the whole database.
+func (bp *boltPersistance) RecordCounts() (counts map[string]int, err error) {
+	counts = make(map[string]int)
+	err = bp.view(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			counts[string(name)] = b.Stats().KeyN
 			return nil
-		}
-		s, err = decodeSecurity(encoded)
-		if err != nil {
-			return err
-		}
-		return nil
+		})
 	})
 	return
 }
 
 type Server struct {
 	Getter
+	// LookupTimeout bounds the time spent resolving the keys of one query; zero
+	// means no limit beyond the client's connection
+	LookupTimeout time.Duration
+	// MaxBodyBytes and MaxKeys, if positive, limit the size of a query; larger
+	// queries are rejected with status 413
+	MaxBodyBytes int64
+	MaxKeys      int
+	// Metrics, if set, records the size and outcomes of each query
+	Metrics *Metrics
+	// Health, if set, adds the server's load history to its info
+	Health *Health
+	// CursorKey encrypts the search page tokens given to clients not entitled to see
+	// CUSIPs.  If empty, a random key is used, and tokens only last until the server
+	// restarts.
+	CursorKey []byte
 }
 
+// QueryHandler responds to a JSON Request with the Securities matching its keys.  A key
+// that fails to resolve does not fail the others: by default it is returned as an empty
+// Security and counted in the X-Lookup-Failures header, and with WithStatus set each
+// key's outcome is reported in a StatusResponse.
 func (s Server) QueryHandler(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
-	data, err := ioutil.ReadAll(r.Body)
+	body := r.Body
+	if s.MaxBodyBytes > 0 {
+		body = http.MaxBytesReader(w, body, s.MaxBodyBytes)
+	}
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("This method accepts request bodies of at most %d bytes.", s.MaxBodyBytes), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if len(data) == 0 {
 		http.Error(w, "This method expects a JSON body containing a request.  This request had a body of length 0.", http.StatusBadRequest)
 		return
@@ -196,14 +341,184 @@ func (s Server) QueryHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	var response []*Security
-	response, err = s.Get(req.Keys...)
+	format, err := ResponseFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	asOf, err := ParseAsOf(req.AsOf)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	maxKeys := s.MaxKeys
+	if c := ClientFrom(r.Context()); c != nil && c.MaxKeys > 0 && (maxKeys <= 0 || c.MaxKeys < maxKeys) {
+		maxKeys = c.MaxKeys
+	}
+	if maxKeys > 0 && len(req.Keys) > maxKeys {
+		http.Error(w, fmt.Sprintf("This method accepts at most %d keys per request; this request had %d.", maxKeys, len(req.Keys)), http.StatusRequestEntityTooLarge)
+		return
+	}
+	ctx := r.Context()
+	if s.LookupTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.LookupTimeout)
+		defer cancel()
+	}
+	entitlement := EntitlementFrom(r.Context())
+	var results []Result
+	results, err = lookupEntitled(ctx, s.Getter, entitlement, req.Keys)
+	if err == context.DeadlineExceeded {
+		http.Error(w, "Lookup timed out after "+s.LookupTimeout.String(), http.StatusGatewayTimeout)
+		return
+	}
+	if err == context.Canceled {
+		// the client has gone away
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	withLifecycle(results, asOf, req.ExcludeTerminated)
+	s.Metrics.ObserveLookups(results)
+	countResults(r.Context(), results)
+	w.Header().Set("Vary", "Accept")
+	if format == StructuredFormat {
+		for i := range results {
+			if results[i].Security != nil {
+				results[i].Security = results[i].Security.Structured()
+			}
+		}
+	}
+	var js []byte
+	if req.WithStatus {
+		js, err = statusResponse(results, entitlement)
+	} else {
+		response := make([]*Security, len(results))
+		var failures, denied, terminated int
+		var failure error
+		for i, result := range results {
+			response[i] = result.Security
+			if result.Security == nil {
+				response[i] = &Security{}
+				if format == StructuredFormat {
+					response[i] = response[i].Structured()
+				}
+			}
+			switch result.Status {
+			case Failed:
+				failures++
+				failure = result.Err
+			case Denied:
+				denied++
+			case Terminated:
+				terminated++
+			}
+		}
+		if failures > 0 {
+			if failures == len(results) {
+				http.Error(w, failure.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("X-Lookup-Failures", strconv.Itoa(failures))
+		}
+		if denied > 0 {
+			w.Header().Set("X-Denied-Keys", strconv.Itoa(denied))
+		}
+		if terminated > 0 {
+			w.Header().Set("X-Terminated-Keys", strconv.Itoa(terminated))
+		}
+		if entitlement.RestrictsFields() {
+			w.Header().Set("X-Denied-Fields", strings.Join(entitlement.DeniedFields(), ","))
+			redacted := make([]json.Marshaler, len(response))
+			for i, sec := range response {
+				redacted[i] = entitlement.Redact(sec)
+			}
+			js, err = json.Marshal(redacted)
+		} else {
+			js, err = ffjson.Marshal(response)
+		}
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+	return
+}
+
+// StructuredMediaType may be sent in an Accept header to request StructuredFormat
+const StructuredMediaType = "application/vnd.fast-lem.structured+json"
+
+// ResponseFormat returns the Description format requested by the format query
+// parameter or, failing that, by the Accept header.  LegacyFormat is the default.
+func ResponseFormat(r *http.Request) (string, error) {
+	switch f := r.URL.Query().Get("format"); f {
+	case "":
+	case LegacyFormat, StructuredFormat:
+		return f, nil
+	default:
+		return "", fmt.Errorf("Unknown format %q; expected %s or %s", f, LegacyFormat, StructuredFormat)
+	}
+	for _, accept := range r.Header["Accept"] {
+		for _, part := range strings.Split(accept, ",") {
+			mediaType, _, err := mime.ParseMediaType(part)
+			if err == nil && mediaType == StructuredMediaType {
+				return StructuredFormat, nil
+			}
+		}
+	}
+	return LegacyFormat, nil
+}
+
+// statusResponse marshals a StatusResponse reporting results, showing only the fields
+// of each Security that e allows
+func statusResponse(results []Result, e *Entitlement) ([]byte, error) {
+	if !e.RestrictsFields() {
+		response := &StatusResponse{Results: make([]*KeyResult, len(results))}
+		for i, result := range results {
+			response.Results[i] = result.KeyResult()
+		}
+		return ffjson.Marshal(response)
+	}
+	response := &entitledStatusResponse{
+		Results:      make([]*entitledKeyResult, len(results)),
+		DeniedFields: e.DeniedFields(),
+	}
+	for i, result := range results {
+		kr := result.KeyResult()
+		response.Results[i] = &entitledKeyResult{Key: kr.Key, Type: kr.Type, Status: kr.Status, Error: kr.Error}
+		if kr.Security != nil {
+			response.Results[i].Security = e.Redact(kr.Security)
+		}
+	}
+	return json.Marshal(response)
+}
+
+// InfoHandler responds with an Info describing the server, the load metadata of the
+// underlying database and the number of records it holds
+func (s Server) InfoHandler(w http.ResponseWriter, r *http.Request) {
+	info := newInfo()
+	s.Health.info(info)
+	var err error
+	if d, ok := s.Getter.(Describer); ok {
+		info.Metadata, err = d.Describe()
+		if err != nil && err != ErrNoMetadata {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if rc, ok := s.Getter.(RecordCounter); ok {
+		info.Records, err = rc.RecordCounts()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
 	var js []byte
-	js, err = ffjson.Marshal(response)
+	js, err = json.Marshal(info)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
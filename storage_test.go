@@ -0,0 +1,166 @@
+package fast_lem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// testStorage returns Storage holding testSecurities in a temporary database
+func testStorage(t testing.TB) (Storage, func()) {
+	f, err := ioutil.TempFile("", "lem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	db, err := bolt.Open(f.Name(), 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup := func() {
+		db.Close()
+		os.Remove(f.Name())
+	}
+	storage, err := NewStorage(db)
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	storage.Store(securityChan(testSecurities()...))
+	return storage, cleanup
+}
+
+func TestBoltGetContext(t *testing.T) {
+	storage, cleanup := testStorage(t)
+	defer cleanup()
+	s := testSecurities()
+	response, err := storage.Get(s[0].CUSIP, s[1].ISIN, s[2].SEDOL, "NOPE00000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{s[0].CUSIP, s[1].CUSIP, s[2].CUSIP, ""}
+	for i, r := range response {
+		if r.CUSIP != want[i] {
+			t.Errorf("Key %d: got CUSIP '%s', want '%s'", i, r.CUSIP, want[i])
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, g := range []Getter{storage, testMaster(t)} {
+		_, err = GetContext(ctx, g, s[0].CUSIP)
+		if err != context.Canceled {
+			t.Errorf("%T: got error %v, want %v", g, err, context.Canceled)
+		}
+	}
+}
+
+// failingGetter fails any batch containing the key "BROKEN000"
+type failingGetter struct {
+	mapGetter
+}
+
+func (fg failingGetter) Get(keys ...string) ([]*Security, error) {
+	for _, k := range keys {
+		if k == "BROKEN000" {
+			return nil, errors.New("corrupt record")
+		}
+	}
+	return fg.mapGetter.Get(keys...)
+}
+
+func TestQueryHandlerPartialResults(t *testing.T) {
+	s := testSecurities()
+	server := Server{Getter: failingGetter{mapGetter{s[0].CUSIP: s[0]}}}
+	query := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.QueryHandler(w, httptest.NewRequest("POST", "/query", strings.NewReader(body)))
+		return w
+	}
+	w := query(`{"Keys":["851500000","BROKEN000"]}`)
+	if w.Code != http.StatusOK || w.Header().Get("X-Lookup-Failures") != "1" {
+		t.Errorf("Got status %d with %q failures, want 200 with 1", w.Code, w.Header().Get("X-Lookup-Failures"))
+	}
+	if !strings.HasPrefix(w.Body.String(), `[{"LegalEntityId":"8C7QCS-E"`) {
+		t.Errorf("Unexpected body %s", w.Body)
+	}
+	w = query(`{"Keys":["851500000","BROKEN000","NOPE00000",""],"WithStatus":true}`)
+	var response struct {
+		Results []struct {
+			Key, Type, Status, Error string
+		}
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err, w.Body)
+	}
+	want := []string{"found", "error", "not_found", "invalid"}
+	for i, r := range response.Results {
+		if r.Status != want[i] {
+			t.Errorf("%q: got status %s, want %s", r.Key, r.Status, want[i])
+		}
+	}
+	if w = query(`{"Keys":["BROKEN000"]}`); w.Code != http.StatusInternalServerError {
+		t.Errorf("Got status %d when every key failed, want 500", w.Code)
+	}
+}
+
+func TestQueryHandlerLimits(t *testing.T) {
+	s := testSecurities()
+	server := Server{Getter: mapGetter{s[0].CUSIP: s[0]}, MaxBodyBytes: 64, MaxKeys: 2}
+	query := func(body string) int {
+		w := httptest.NewRecorder()
+		server.QueryHandler(w, httptest.NewRequest("POST", "/query", strings.NewReader(body)))
+		return w.Code
+	}
+	if code := query(`{"Keys":["851500000","NOPE00000"]}`); code != http.StatusOK {
+		t.Errorf("Got status %d within the limits, want 200", code)
+	}
+	if code := query(`{"Keys":["1","2","3"]}`); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Got status %d for too many keys, want 413", code)
+	}
+	if code := query(`{"Keys":["` + strings.Repeat("X", 100) + `"]}`); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Got status %d for an oversized body, want 413", code)
+	}
+}
+
+func TestQueryHandlerFormats(t *testing.T) {
+	s := testSecurities()
+	server := Server{Getter: mapGetter{s[0].CUSIP: s[0]}}
+	query := func(target, accept string) string {
+		r := httptest.NewRequest("POST", target, strings.NewReader(`{"Keys":["851500000"]}`))
+		if len(accept) > 0 {
+			r.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		server.QueryHandler(w, r)
+		return w.Body.String()
+	}
+	legacy := `"Description":"` + s[0].Description.IssueType.String() + `  5.00% 2007/07/01","Status":"terminated"}]`
+	if body := query("/query", ""); !strings.HasSuffix(body, legacy) {
+		t.Errorf("Got %s, want the legacy description", body)
+	}
+	structured := `"Description":{"IssueType":"MU","IssueTypeLabel":"` + s[0].Description.IssueType.String() +
+		`","AssetClass":"Fixed Income","Coupon":5,"Maturity":"2007-07-01"},"Status":"terminated"}]`
+	for _, body := range []string{
+		query("/query?format=structured", ""),
+		query("/query", "text/plain, "+StructuredMediaType+"; q=0.9"),
+	} {
+		if !strings.HasSuffix(body, structured) {
+			t.Errorf("Got %s, want %s", body, structured)
+		}
+	}
+	if s[0].Description.structured {
+		t.Error("Structured modified the looked-up Security")
+	}
+	if body := query("/query?format=xml", ""); !strings.HasPrefix(body, "Unknown format") {
+		t.Errorf("Got %s for an unknown format", body)
+	}
+}
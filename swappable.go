@@ -0,0 +1,177 @@
+package fast_lem
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// Swappable is a Getter whose underlying Getter can be replaced, for example by a
+// freshly loaded database, while it serves lookups
+type Swappable struct {
+	mu       sync.RWMutex
+	g        Getter
+	swapping int32
+	onSwap   []func()
+	// generation counts the swaps, so that what is derived from g can be kept until
+	// the next one
+	generation uint64
+
+	countsMu  sync.Mutex
+	counts    map[string]int
+	countsGen uint64
+}
+
+// ErrNotLoaded is returned by lookups made before any data has been swapped in
+var ErrNotLoaded = errors.New("no security data loaded")
+
+// notLoaded stands in for the Getter of a Swappable that has none yet
+type notLoaded struct{}
+
+func (notLoaded) Get(keys ...string) ([]*Security, error) {
+	return nil, ErrNotLoaded
+}
+
+func (notLoaded) Search(ctx context.Context, f *Filter) (*SearchResponse, error) {
+	return nil, ErrNotLoaded
+}
+
+func (notLoaded) ParentChain(ctx context.Context, id string) ([]Entity, error) {
+	return nil, ErrNotLoaded
+}
+
+func (notLoaded) Descendants(ctx context.Context, id string) ([]string, error) {
+	return nil, ErrNotLoaded
+}
+
+// NewSwappable returns a Swappable serving lookups from g.  If g is nil, lookups fail
+// with ErrNotLoaded until the first Swap.
+func NewSwappable(g Getter) *Swappable {
+	if g == nil {
+		g = notLoaded{}
+	}
+	return &Swappable{g: g}
+}
+
+// OnSwap registers fn to be called after each swap, such as Cache.Purge for a cache
+// in front of the Swappable
+func (s *Swappable) OnSwap(fn func()) {
+	s.mu.Lock()
+	s.onSwap = append(s.onSwap, fn)
+	s.mu.Unlock()
+}
+
+// Swap replaces the underlying Getter with g once lookups in flight have finished,
+// and returns the previous Getter, which is then no longer in use
+func (s *Swappable) Swap(g Getter) (old Getter) {
+	atomic.StoreInt32(&s.swapping, 1)
+	defer atomic.StoreInt32(&s.swapping, 0)
+	s.mu.Lock()
+	old, s.g = s.g, g
+	s.generation++
+	callbacks := s.onSwap
+	s.mu.Unlock()
+	for _, fn := range callbacks {
+		fn()
+	}
+	return
+}
+
+// Swapping reports whether a swap is waiting for lookups in flight to finish
+func (s *Swappable) Swapping() bool {
+	return atomic.LoadInt32(&s.swapping) == 1
+}
+
+// Loaded reports whether any data has been swapped in
+func (s *Swappable) Loaded() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, none := s.g.(notLoaded)
+	return !none
+}
+
+// withCurrent calls fn with the Getter lookups are currently served from and the
+// number of swaps that have installed it.  The Getter must not be used once fn
+// returns, as a swap may then close it.
+func (s *Swappable) withCurrent(fn func(g Getter, generation uint64)) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	fn(s.g, s.generation)
+}
+
+// Get "hydrates" security details from one or more identifiers
+func (s *Swappable) Get(keys ...string) ([]*Security, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.g.Get(keys...)
+}
+
+// GetContext "hydrates" security details from one or more identifiers, giving up if
+// ctx is done first
+func (s *Swappable) GetContext(ctx context.Context, keys ...string) ([]*Security, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return GetContext(ctx, s.g, keys...)
+}
+
+// Lookup resolves each key independently
+func (s *Swappable) Lookup(ctx context.Context, keys ...string) ([]Result, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return Lookup(ctx, s.g, keys...)
+}
+
+// Search pages through the Securities currently served that match f
+func (s *Swappable) Search(ctx context.Context, f *Filter) (*SearchResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return Search(ctx, s.g, f)
+}
+
+// ParentChain returns the parent chain of the entity with id from the data currently
+// served
+func (s *Swappable) ParentChain(ctx context.Context, id string) ([]Entity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return ParentChain(ctx, s.g, id)
+}
+
+// Descendants returns id and every entity beneath it from the data currently served
+func (s *Swappable) Descendants(ctx context.Context, id string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return Descendants(ctx, s.g, id)
+}
+
+// Describe returns the provenance of the data currently served
+func (s *Swappable) Describe() (*Metadata, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if d, ok := s.g.(Describer); ok {
+		return d.Describe()
+	}
+	return nil, ErrNoMetadata
+}
+
+// RecordCounts counts the records currently served.  Counting can read the whole
+// database, so the counts are kept until the next swap.
+func (s *Swappable) RecordCounts() (map[string]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.countsMu.Lock()
+	defer s.countsMu.Unlock()
+	if s.counts != nil && s.countsGen == s.generation {
+		return s.counts, nil
+	}
+	rc, ok := s.g.(RecordCounter)
+	if !ok {
+		return nil, nil
+	}
+	counts, err := rc.RecordCounts()
+	if err != nil {
+		return nil, err
+	}
+	s.counts, s.countsGen = counts, s.generation
+	return counts, nil
+}